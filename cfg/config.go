@@ -1,13 +1,46 @@
 package cfg
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/qosmioo/merch-store/pkg/jwt"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
+	Server struct {
+		Port int `yaml:"port"`
+		// Таймауты http.Server. Ноль отключает соответствующее ограничение
+		ReadTimeout       time.Duration `yaml:"read_timeout"`
+		ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+		WriteTimeout      time.Duration `yaml:"write_timeout"`
+		IdleTimeout       time.Duration `yaml:"idle_timeout"`
+		// DrainDelay — сколько после сигнала остановки /readyz отвечает 503, а
+		// сервер ещё принимает запросы, пока балансировщик не исключит экземпляр
+		DrainDelay time.Duration `yaml:"drain_delay"`
+		// ShutdownTimeout — сколько при остановке ждать завершения начатых запросов
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+		TLS             struct {
+			// CertFile и KeyFile включают HTTPS. Сертификат перечитывается по
+			// SIGHUP и раз в ReloadInterval, если файлы изменились
+			CertFile       string        `yaml:"cert_file"`
+			KeyFile        string        `yaml:"key_file"`
+			ReloadInterval time.Duration `yaml:"reload_interval"`
+		} `yaml:"tls"`
+		// LegacyBuyGet оставляет устаревший маршрут GET /api/buy/{item}
+		LegacyBuyGet bool `yaml:"legacy_buy_get"`
+		// MetricsPort — порт отдельного HTTP-сервера с /metrics, который не
+		// следует открывать наружу. Ноль отключает метрики
+		MetricsPort int `yaml:"metrics_port"`
+	} `yaml:"server"`
 	Database struct {
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
@@ -15,32 +48,240 @@ type Config struct {
 		Host     string `yaml:"host"`
 		Port     string `yaml:"port"`
 		SSLMode  string `yaml:"sslmode"`
+		// QueryTimeout ограничивает время работы с базой в рамках одного запроса
+		QueryTimeout   time.Duration `yaml:"query_timeout"`
+		ConnectTimeout time.Duration `yaml:"connect_timeout"`
+		// MaxConns и MinConns задают размер пула соединений
+		MaxConns        int           `yaml:"max_conns"`
+		MinConns        int           `yaml:"min_conns"`
+		MaxConnLifetime time.Duration `yaml:"max_conn_lifetime"`
+		MaxConnIdleTime time.Duration `yaml:"max_conn_idle_time"`
 	} `yaml:"database"`
+	Auth struct {
+		AutoProvision   bool          `yaml:"auto_provision"`
+		RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+		// PasswordHasher — алгоритм новых хэшей паролей: bcrypt или argon2id.
+		// Хэши другого алгоритма принимаются и заменяются при следующем входе
+		PasswordHasher string `yaml:"password_hasher"`
+	} `yaml:"auth"`
+	Employees struct {
+		// StartingCoins — баланс нового сотрудника
+		StartingCoins int `yaml:"starting_coins"`
+	} `yaml:"employees"`
+	Idempotency struct {
+		// TTL — сколько хранится ответ на запрос с Idempotency-Key
+		TTL time.Duration `yaml:"ttl"`
+		// Lease — сколько ключ занят запросом, который не завершился, например
+		// из-за падения процесса. Должно превышать время обработки запроса
+		Lease           time.Duration `yaml:"lease"`
+		CleanupInterval time.Duration `yaml:"cleanup_interval"`
+	} `yaml:"idempotency"`
+	JWT jwt.Config `yaml:"jwt"`
 }
 
-func LoadConfig() (*Config, error) {
-	file, err := os.Open("cfg/config.yaml")
-	if err != nil {
+// Default возвращает конфигурацию, с которой сервис запускается без файла
+// настроек. Секрет JWT и пароль базы данных задаются отдельно
+func Default() *Config {
+	var config Config
+	config.Server.Port = 8080
+	config.Server.ReadTimeout = 10 * time.Second
+	config.Server.ReadHeaderTimeout = 5 * time.Second
+	config.Server.WriteTimeout = 30 * time.Second
+	config.Server.IdleTimeout = 2 * time.Minute
+	config.Server.DrainDelay = 5 * time.Second
+	config.Server.ShutdownTimeout = 20 * time.Second
+	config.Server.TLS.ReloadInterval = time.Minute
+	config.Server.LegacyBuyGet = true
+	config.Server.MetricsPort = 9090
+	config.Database.User = "postgres"
+	config.Database.DBName = "merch_store"
+	config.Database.Host = "localhost"
+	config.Database.Port = "5432"
+	config.Database.SSLMode = "disable"
+	config.Database.QueryTimeout = 5 * time.Second
+	config.Database.ConnectTimeout = 5 * time.Second
+	config.Database.MaxConns = 10
+	config.Database.MaxConnLifetime = time.Hour
+	config.Database.MaxConnIdleTime = 30 * time.Minute
+	config.Auth.RefreshTokenTTL = 30 * 24 * time.Hour
+	config.Auth.PasswordHasher = "bcrypt"
+	config.Employees.StartingCoins = 1000
+	config.Idempotency.TTL = 24 * time.Hour
+	config.Idempotency.Lease = 2 * time.Minute
+	config.Idempotency.CleanupInterval = time.Hour
+	config.JWT = jwt.Config{
+		Issuer:       "merch-store",
+		Audience:     "merch-store",
+		TTL:          15 * time.Minute,
+		SigningKeyID: "default",
+		Keys:         []jwt.KeyConfig{{ID: "default", Algorithm: "HS256", SecretEnv: "JWT_SECRET"}},
+	}
+	return &config
+}
+
+// Mode — команда, для которой загружается конфигурация
+type Mode int
+
+const (
+	// ModeServe — запуск HTTP-сервера, проверяются все параметры
+	ModeServe Mode = iota
+	// ModeMigrate — команда migrate, проверяются только параметры базы данных
+	ModeMigrate
+)
+
+// Load собирает конфигурацию по слоям: значения по умолчанию, YAML-файл из
+// флага --config, переменные окружения и флаги командной строки. Каждый
+// следующий слой переопределяет предыдущий. lookupEnv обычно os.LookupEnv
+func Load(args []string, mode Mode, lookupEnv func(string) (string, bool)) (*Config, error) {
+	config := Default()
+	settings := config.settings()
+
+	fs := flag.NewFlagSet("merch-store", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	path := fs.String("config", "", "path to the YAML config file")
+	flagValues := make(map[string]string)
+	for _, s := range settings {
+		s := s
+		record := func(value string) error {
+			flagValues[s.key] = value
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", s.key, s.env)
+		if s.boolean {
+			fs.BoolFunc(s.flag, usage, record)
+		} else {
+			fs.Func(s.flag, usage, record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *path != "" {
+		if err := config.readFile(*path); err != nil {
+			return nil, err
+		}
+	}
+	for _, s := range settings {
+		if value, ok := lookupEnv(s.env); ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("environment variable %s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range settings {
+		if value, ok := flagValues[s.key]; ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("flag --%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := config.Validate(mode, lookupEnv); err != nil {
 		return nil, err
 	}
+	return config, nil
+}
+
+func (c *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
 	defer file.Close()
 
-	var config Config
+	// Неизвестные ключи считаются ошибкой, чтобы опечатка в названии
+	// параметра не оставляла его молча со значением по умолчанию
 	decoder := yaml.NewDecoder(file)
-	if err := decoder.Decode(&config); err != nil {
-		return nil, err
+	decoder.SetStrict(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate проверяет параметры, которые использует команда mode, и
+// возвращает по одной строке на каждое нарушение. Миграциям нужна только
+// база данных, поэтому, например, секрет JWT для них не требуется
+func (c *Config) Validate(mode Mode, lookupEnv func(string) (string, bool)) error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
 
-	return &config, nil
+	check(c.Database.Host != "", "database.host is required")
+	dbPort, err := strconv.Atoi(c.Database.Port)
+	check(err == nil && dbPort > 0 && dbPort <= 65535, "database.port must be a number between 1 and 65535, got %q", c.Database.Port)
+	check(c.Database.User != "", "database.user is required")
+	check(c.Database.DBName != "", "database.dbname is required")
+	check(sslModes[c.Database.SSLMode], "database.sslmode must be one of disable, allow, prefer, require, verify-ca, verify-full, got %q", c.Database.SSLMode)
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout must not be negative, got %s", c.Database.QueryTimeout)
+	check(c.Database.ConnectTimeout >= 0, "database.connect_timeout must not be negative, got %s", c.Database.ConnectTimeout)
+	check(c.Database.MaxConns >= 1, "database.max_conns must be at least 1, got %d", c.Database.MaxConns)
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns, "database.min_conns must be between 0 and database.max_conns (%d), got %d", c.Database.MaxConns, c.Database.MinConns)
+	check(c.Database.MaxConnLifetime >= 0, "database.max_conn_lifetime must not be negative, got %s", c.Database.MaxConnLifetime)
+	check(c.Database.MaxConnIdleTime >= 0, "database.max_conn_idle_time must not be negative, got %s", c.Database.MaxConnIdleTime)
+
+	if mode == ModeServe {
+		check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+		check(c.Server.MetricsPort >= 0 && c.Server.MetricsPort <= 65535, "server.metrics_port must be between 0 and 65535, got %d", c.Server.MetricsPort)
+		check(c.Server.MetricsPort == 0 || c.Server.MetricsPort != c.Server.Port, "server.metrics_port must differ from server.port %d", c.Server.Port)
+		check(c.Server.ReadTimeout >= 0, "server.read_timeout must not be negative, got %s", c.Server.ReadTimeout)
+		check(c.Server.ReadHeaderTimeout >= 0, "server.read_header_timeout must not be negative, got %s", c.Server.ReadHeaderTimeout)
+		check(c.Server.WriteTimeout >= 0, "server.write_timeout must not be negative, got %s", c.Server.WriteTimeout)
+		check(c.Server.IdleTimeout >= 0, "server.idle_timeout must not be negative, got %s", c.Server.IdleTimeout)
+		check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative, got %s", c.Server.DrainDelay)
+		check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive, got %s", c.Server.ShutdownTimeout)
+		check((c.Server.TLS.CertFile == "") == (c.Server.TLS.KeyFile == ""), "server.tls.cert_file and server.tls.key_file must be set together")
+		check(c.Server.TLS.ReloadInterval >= 0, "server.tls.reload_interval must not be negative, got %s", c.Server.TLS.ReloadInterval)
+
+		check(c.Auth.RefreshTokenTTL > 0, "auth.refresh_token_ttl must be positive, got %s", c.Auth.RefreshTokenTTL)
+		check(c.Auth.PasswordHasher == "bcrypt" || c.Auth.PasswordHasher == "argon2id", "auth.password_hasher must be bcrypt or argon2id, got %q", c.Auth.PasswordHasher)
+		check(c.Employees.StartingCoins >= 0, "employees.starting_coins must not be negative, got %d", c.Employees.StartingCoins)
+		check(c.Idempotency.TTL > 0, "idempotency.ttl must be positive, got %s", c.Idempotency.TTL)
+		check(c.Idempotency.Lease > 0, "idempotency.lease must be positive, got %s", c.Idempotency.Lease)
+		check(c.Idempotency.CleanupInterval >= 0, "idempotency.cleanup_interval must not be negative, got %s", c.Idempotency.CleanupInterval)
+
+		check(c.JWT.Issuer != "", "jwt.issuer is required")
+		check(c.JWT.Audience != "", "jwt.audience is required")
+		check(c.JWT.TTL > 0, "jwt.ttl must be positive, got %s", c.JWT.TTL)
+		check(len(c.JWT.Keys) > 0, "jwt.keys must contain at least one key")
+		signingKeyFound := false
+		for i, key := range c.JWT.Keys {
+			check(key.ID != "", "jwt.keys[%d].id is required", i)
+			signingKeyFound = signingKeyFound || key.ID == c.JWT.SigningKeyID
+			if key.SecretEnv != "" {
+				_, ok := lookupEnv(key.SecretEnv)
+				check(ok, "jwt.keys[%d] (%q) reads its secret from environment variable %s, which is not set", i, key.ID, key.SecretEnv)
+			}
+		}
+		check(signingKeyFound, "jwt.signing_key_id %q does not match any key in jwt.keys", c.JWT.SigningKeyID)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
 }
 
+var sslModes = map[string]bool{"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true}
+
 func (c *Config) GetDatabaseURL() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		c.Database.User,
-		c.Database.Password,
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.DBName,
-		c.Database.SSLMode,
-	)
+	databaseURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Database.User, c.Database.Password),
+		Host:     c.Database.Host + ":" + c.Database.Port,
+		Path:     c.Database.DBName,
+		RawQuery: "sslmode=" + url.QueryEscape(c.Database.SSLMode),
+	}
+	return databaseURL.String()
 }
@@ -0,0 +1,175 @@
+package cfg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	config, err := Load(nil, ModeServe, envLookup(map[string]string{"JWT_SECRET": "secret"}))
+	require.NoError(t, err)
+	assert.Equal(t, 8080, config.Server.Port)
+	assert.Equal(t, 10, config.Database.MaxConns)
+	assert.Equal(t, 1000, config.Employees.StartingCoins)
+	assert.Equal(t, "postgres://postgres:@localhost:5432/merch_store?sslmode=disable", config.GetDatabaseURL())
+}
+
+func TestLoad_Layers(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  port: 9000
+database:
+  host: db-from-file
+  user: file-user
+  max_conns: 20
+employees:
+  starting_coins: 500
+`)
+	env := map[string]string{
+		"JWT_SECRET":         "secret",
+		"DATABASE_HOST":      "db-from-env",
+		"DATABASE_NAME":      "shop",
+		"DATABASE_MAX_CONNS": "30",
+		"SERVER_PORT":        "9100",
+	}
+	config, err := Load([]string{"--config", path, "--server-port", "9200", "--auth-auto-provision"}, ModeServe, envLookup(env))
+	require.NoError(t, err)
+
+	// Флаг важнее окружения, окружение важнее файла, файл важнее значений по умолчанию
+	assert.Equal(t, 9200, config.Server.Port)
+	assert.Equal(t, "db-from-env", config.Database.Host)
+	assert.Equal(t, 30, config.Database.MaxConns)
+	assert.Equal(t, "shop", config.Database.DBName)
+	assert.Equal(t, "file-user", config.Database.User)
+	assert.Equal(t, 500, config.Employees.StartingCoins)
+	assert.True(t, config.Auth.AutoProvision)
+	assert.Equal(t, 5*time.Second, config.Database.QueryTimeout)
+}
+
+func TestLoad_InvalidValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		file     string
+		noSecret bool
+		message  string
+	}{
+		{
+			name:    "env is not an integer",
+			env:     map[string]string{"DATABASE_MAX_CONNS": "many"},
+			message: `environment variable DATABASE_MAX_CONNS: database.max_conns must be an integer, got "many"`,
+		},
+		{
+			name:    "flag is not a duration",
+			args:    []string{"--jwt-ttl", "15"},
+			message: `flag --jwt-ttl: jwt.ttl must be a duration such as 30s or 1h, got "15"`,
+		},
+		{
+			name:    "unknown key in file",
+			file:    "database:\n  hots: db\n",
+			message: "field hots not found",
+		},
+		{
+			name:    "port out of range",
+			env:     map[string]string{"SERVER_PORT": "70000"},
+			message: "server.port must be between 1 and 65535, got 70000",
+		},
+		{
+			name:    "metrics on the public port",
+			args:    []string{"--server-metrics-port", "8080"},
+			message: "server.metrics_port must differ from server.port 8080",
+		},
+		{
+			name:    "pool sizes",
+			args:    []string{"--database-max-conns", "2", "--database-min-conns", "5"},
+			message: "database.min_conns must be between 0 and database.max_conns (2), got 5",
+		},
+		{
+			name:    "negative starting coins",
+			env:     map[string]string{"EMPLOYEES_STARTING_COINS": "-1"},
+			message: "employees.starting_coins must not be negative, got -1",
+		},
+		{
+			name:    "unknown signing key",
+			args:    []string{"--jwt-signing-key-id", "next"},
+			message: `jwt.signing_key_id "next" does not match any key in jwt.keys`,
+		},
+		{
+			name:    "tls key without certificate",
+			env:     map[string]string{"SERVER_TLS_KEY_FILE": "/etc/merch-store/tls.key"},
+			message: "server.tls.cert_file and server.tls.key_file must be set together",
+		},
+		{
+			name:    "zero shutdown timeout",
+			args:    []string{"--server-shutdown-timeout", "0s"},
+			message: "server.shutdown_timeout must be positive, got 0s",
+		},
+		{
+			name:     "missing secret",
+			noSecret: true,
+			message:  `jwt.keys[0] ("default") reads its secret from environment variable JWT_SECRET, which is not set`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"JWT_SECRET": "secret"}
+			if tt.noSecret {
+				delete(env, "JWT_SECRET")
+			}
+			for name, value := range tt.env {
+				env[name] = value
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"--config", writeConfig(t, tt.file)}, args...)
+			}
+
+			_, err := Load(args, ModeServe, envLookup(env))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.message)
+		})
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	config := Default()
+	config.Server.Port = 0
+	config.Database.Host = ""
+	config.Database.SSLMode = "sometimes"
+
+	err := config.Validate(ModeServe, envLookup(map[string]string{"JWT_SECRET": "secret"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.port must be between 1 and 65535, got 0")
+	assert.Contains(t, err.Error(), "database.host is required")
+	assert.Contains(t, err.Error(), `database.sslmode must be one of disable, allow, prefer, require, verify-ca, verify-full, got "sometimes"`)
+}
+
+func TestLoad_MigrateChecksOnlyDatabase(t *testing.T) {
+	// migrate не подписывает токены и не слушает порты, поэтому JWT_SECRET не нужен
+	config, err := Load([]string{"--server-port", "0"}, ModeMigrate, envLookup(map[string]string{"DATABASE_HOST": "db"}))
+	require.NoError(t, err)
+	assert.Equal(t, "db", config.Database.Host)
+
+	_, err = Load([]string{"--database-sslmode", "sometimes"}, ModeMigrate, envLookup(nil))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `database.sslmode must be one of`)
+}
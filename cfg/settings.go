@@ -0,0 +1,119 @@
+package cfg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting — параметр, который можно переопределить переменной окружения и
+// флагом. Имена выводятся из ключа: database.max_conns задаётся переменной
+// DATABASE_MAX_CONNS и флагом --database-max-conns
+type setting struct {
+	key     string
+	env     string
+	flag    string
+	boolean bool
+	set     func(value string) error
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		intSetting("server.port", &c.Server.Port),
+		durationSetting("server.read_timeout", &c.Server.ReadTimeout),
+		durationSetting("server.read_header_timeout", &c.Server.ReadHeaderTimeout),
+		durationSetting("server.write_timeout", &c.Server.WriteTimeout),
+		durationSetting("server.idle_timeout", &c.Server.IdleTimeout),
+		durationSetting("server.drain_delay", &c.Server.DrainDelay),
+		durationSetting("server.shutdown_timeout", &c.Server.ShutdownTimeout),
+		stringSetting("server.tls.cert_file", &c.Server.TLS.CertFile),
+		stringSetting("server.tls.key_file", &c.Server.TLS.KeyFile),
+		durationSetting("server.tls.reload_interval", &c.Server.TLS.ReloadInterval),
+		boolSetting("server.legacy_buy_get", &c.Server.LegacyBuyGet),
+		intSetting("server.metrics_port", &c.Server.MetricsPort),
+
+		stringSetting("database.host", &c.Database.Host),
+		stringSetting("database.port", &c.Database.Port),
+		stringSetting("database.user", &c.Database.User),
+		stringSetting("database.password", &c.Database.Password),
+		// DATABASE_NAME исторически задаётся в docker-compose.yml
+		withEnv(stringSetting("database.dbname", &c.Database.DBName), "DATABASE_NAME"),
+		stringSetting("database.sslmode", &c.Database.SSLMode),
+		durationSetting("database.query_timeout", &c.Database.QueryTimeout),
+		durationSetting("database.connect_timeout", &c.Database.ConnectTimeout),
+		intSetting("database.max_conns", &c.Database.MaxConns),
+		intSetting("database.min_conns", &c.Database.MinConns),
+		durationSetting("database.max_conn_lifetime", &c.Database.MaxConnLifetime),
+		durationSetting("database.max_conn_idle_time", &c.Database.MaxConnIdleTime),
+
+		boolSetting("auth.auto_provision", &c.Auth.AutoProvision),
+		durationSetting("auth.refresh_token_ttl", &c.Auth.RefreshTokenTTL),
+		stringSetting("auth.password_hasher", &c.Auth.PasswordHasher),
+		intSetting("employees.starting_coins", &c.Employees.StartingCoins),
+		durationSetting("idempotency.ttl", &c.Idempotency.TTL),
+		durationSetting("idempotency.lease", &c.Idempotency.Lease),
+		durationSetting("idempotency.cleanup_interval", &c.Idempotency.CleanupInterval),
+
+		stringSetting("jwt.issuer", &c.JWT.Issuer),
+		stringSetting("jwt.audience", &c.JWT.Audience),
+		durationSetting("jwt.ttl", &c.JWT.TTL),
+		stringSetting("jwt.signing_key_id", &c.JWT.SigningKeyID),
+	}
+}
+
+func newSetting(key string, set func(string) error) setting {
+	return setting{
+		key:  key,
+		env:  strings.ToUpper(strings.NewReplacer(".", "_").Replace(key)),
+		flag: strings.NewReplacer(".", "-", "_", "-").Replace(key),
+		set:  set,
+	}
+}
+
+func withEnv(s setting, env string) setting {
+	s.env = env
+	return s
+}
+
+func stringSetting(key string, field *string) setting {
+	return newSetting(key, func(value string) error {
+		*field = value
+		return nil
+	})
+}
+
+func intSetting(key string, field *int) setting {
+	return newSetting(key, func(value string) error {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", key, value)
+		}
+		*field = parsed
+		return nil
+	})
+}
+
+func boolSetting(key string, field *bool) setting {
+	s := newSetting(key, func(value string) error {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", key, value)
+		}
+		*field = parsed
+		return nil
+	})
+	s.boolean = true
+	return s
+}
+
+func durationSetting(key string, field *time.Duration) setting {
+	return newSetting(key, func(value string) error {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a duration such as 30s or 1h, got %q", key, value)
+		}
+		*field = parsed
+		return nil
+	})
+}
@@ -2,41 +2,198 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/qosmioo/merch-store/cfg"
 	httpHandler "github.com/qosmioo/merch-store/internal/delivery/http"
 	"github.com/qosmioo/merch-store/internal/repository"
 	"github.com/qosmioo/merch-store/internal/usecase"
+	"github.com/qosmioo/merch-store/migrations"
+	"github.com/qosmioo/merch-store/pkg/hasher"
+	"github.com/qosmioo/merch-store/pkg/jwt"
 	"github.com/qosmioo/merch-store/pkg/logger"
+	"github.com/qosmioo/merch-store/pkg/migrate"
 )
 
 func main() {
 	logger := logger.InitLogger()
 
-	config, err := cfg.LoadConfig()
+	migrateCommand, args, isMigrate := splitMigrateArgs(os.Args[1:])
+	mode := cfg.ModeServe
+	if isMigrate {
+		mode = cfg.ModeMigrate
+	}
+	config, err := cfg.Load(args, mode, os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
 		log.Fatalf("Unable to load config: %v\n", err)
 	}
 
-	databaseURL := config.GetDatabaseURL()
+	// SIGTERM и SIGINT останавливают приём запросов, начатые запросы дорабатывают
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	dbpool, err := pgxpool.Connect(context.Background(), databaseURL)
+	dbpool, err := connectDatabase(ctx, config)
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v\n", err)
 	}
-	defer dbpool.Close()
+
+	if isMigrate {
+		err := runMigrate(ctx, dbpool, migrateCommand, os.Stdout, logger)
+		dbpool.Close()
+		if err != nil {
+			log.Fatalf("Migration failed: %v\n", err)
+		}
+		return
+	}
+
+	tokenManager, err := jwt.NewManager(config.JWT)
+	if err != nil {
+		log.Fatalf("Unable to configure JWT: %v\n", err)
+	}
 
 	employeeRepo := repository.NewEmployeeRepository(dbpool, logger)
-	employeeUsecase := usecase.NewEmployeeUsecase(employeeRepo, logger)
+	tokenRepo := repository.NewTokenRepository(dbpool, logger)
+	authUsecase := usecase.NewAuthUsecase(tokenRepo, tokenManager, tokenManager, usecase.AuthConfig{
+		RefreshTokenTTL: config.Auth.RefreshTokenTTL,
+		QueryTimeout:    config.Database.QueryTimeout,
+	}, logger)
+	passwordHasher := newPasswordHasher(config.Auth.PasswordHasher)
+	employeeConfig := usecase.EmployeeConfig{
+		AutoProvision: config.Auth.AutoProvision,
+		StartingCoins: config.Employees.StartingCoins,
+		QueryTimeout:  config.Database.QueryTimeout,
+	}
+	employeeUsecase := usecase.NewEmployeeUsecase(employeeRepo, passwordHasher, authUsecase, employeeConfig, logger)
+	merchRepo := repository.NewMerchRepository(dbpool, logger)
+	merchUsecase := usecase.NewMerchUsecase(merchRepo, usecase.MerchConfig{
+		QueryTimeout: config.Database.QueryTimeout,
+	}, logger)
+	ledgerRepo := repository.NewLedgerRepository(dbpool, logger)
+	ledgerUsecase := usecase.NewLedgerUsecase(ledgerRepo, logger)
+	cartRepo := repository.NewCartRepository(dbpool, logger)
+	cartUsecase := usecase.NewCartUsecase(cartRepo, employeeRepo, usecase.CartConfig{
+		QueryTimeout: config.Database.QueryTimeout,
+	}, logger)
+	idempotencyRepo := repository.NewIdempotencyRepository(dbpool, logger)
+	idempotencyUsecase := usecase.NewIdempotencyUsecase(idempotencyRepo, usecase.IdempotencyConfig{
+		TTL:   config.Idempotency.TTL,
+		Lease: config.Idempotency.Lease,
+	}, logger)
+	if config.Idempotency.CleanupInterval > 0 {
+		go idempotencyUsecase.RunCleanup(ctx, config.Idempotency.CleanupInterval)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		repository.NewPoolCollector(dbpool),
+	)
+	httpMetrics := httpHandler.NewMetrics(registry)
 
 	router := mux.NewRouter()
-	handler := httpHandler.NewHandler(employeeUsecase, logger)
+	router.Use(httpMetrics.Middleware)
+	handler := httpHandler.NewHandler(employeeUsecase, authUsecase, merchUsecase, ledgerUsecase, idempotencyUsecase, cartUsecase, httpHandler.Config{
+		LegacyBuyGet: config.Server.LegacyBuyGet,
+		Metrics:      httpMetrics,
+	}, logger)
 	handler.RegisterRoutes(router)
 
-	log.Println("Server is running on port 8080")
-	http.ListenAndServe(":8080", router)
+	migrationList, err := migrate.Load(migrations.FS)
+	if err != nil {
+		log.Fatalf("Unable to load migrations: %v\n", err)
+	}
+	migrator := migrate.New(dbpool, migrationList, logger)
+	healthHandler := httpHandler.NewHealthHandler(buildInfo(), []httpHandler.ReadinessCheck{
+		{Name: "database", Check: dbpool.Ping},
+		{Name: "migrations", Check: func(ctx context.Context) error {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if pending > 0 {
+				return fmt.Errorf("%d migrations are not applied", pending)
+			}
+			return nil
+		}},
+	}, logger)
+	healthHandler.RegisterRoutes(router)
+
+	server, err := newServer(ctx, config, router)
+	if err != nil {
+		log.Fatalf("Unable to configure server: %v\n", err)
+	}
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		log.Fatalf("Unable to listen on %s: %v\n", server.Addr, err)
+	}
+	log.Printf("Server is running on %s\n", server.Addr)
+	if config.Server.MetricsPort > 0 {
+		metricsServer := newMetricsServer(config, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+		metricsListener, err := net.Listen("tcp", metricsServer.Addr)
+		if err != nil {
+			log.Fatalf("Unable to listen on %s: %v\n", metricsServer.Addr, err)
+		}
+		log.Printf("Metrics are served on %s\n", metricsServer.Addr)
+		go func() {
+			err := serve(ctx, metricsServer, metricsListener, shutdownConfig{
+				DrainDelay: config.Server.DrainDelay,
+				Timeout:    config.Server.ShutdownTimeout,
+			})
+			if err != nil {
+				log.Printf("Metrics server stopped: %v\n", err)
+			}
+		}()
+	}
+	err = serve(ctx, server, listener, shutdownConfig{
+		OnDrain:    healthHandler.SetDraining,
+		DrainDelay: config.Server.DrainDelay,
+		Timeout:    config.Server.ShutdownTimeout,
+	})
+	// Пул закрывается только после того, как начатые запросы завершились
+	dbpool.Close()
+	if err != nil {
+		log.Fatalf("Server stopped: %v\n", err)
+	}
+	log.Println("Server stopped")
+}
+
+func connectDatabase(ctx context.Context, config *cfg.Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(config.GetDatabaseURL())
+	if err != nil {
+		return nil, err
+	}
+	poolConfig.MaxConns = int32(config.Database.MaxConns)
+	poolConfig.MinConns = int32(config.Database.MinConns)
+	poolConfig.MaxConnLifetime = config.Database.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = config.Database.MaxConnIdleTime
+	poolConfig.ConnConfig.ConnectTimeout = config.Database.ConnectTimeout
+	return pgxpool.ConnectConfig(ctx, poolConfig)
+}
+
+// newPasswordHasher создаёт хэши выбранным алгоритмом, но принимает хэши
+// другого. Такие хэши заменяются при следующем входе, поэтому алгоритм можно
+// сменить в любую сторону
+func newPasswordHasher(algorithm string) hasher.Hasher {
+	bcrypt := hasher.NewBcrypt(hasher.DefaultBcryptCost)
+	argon2id := hasher.NewArgon2id(hasher.DefaultArgon2Params)
+	if algorithm == "argon2id" {
+		return hasher.New(argon2id, bcrypt)
+	}
+	return hasher.New(bcrypt, argon2id)
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/qosmioo/merch-store/migrations"
+	"github.com/qosmioo/merch-store/pkg/migrate"
+	"go.uber.org/zap"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up|down|status|goto N|force N [config flags]")
+
+// splitMigrateArgs отделяет команду migrate от флагов конфигурации. Слова
+// команды идут до первого флага: migrate goto 5 --config cfg/config.yaml.
+// ok равен false, если программа запущена не командой migrate
+func splitMigrateArgs(args []string) (command, configArgs []string, ok bool) {
+	if len(args) == 0 || args[0] != "migrate" {
+		return nil, args, false
+	}
+	n := 1
+	for n < len(args) && !strings.HasPrefix(args[n], "-") {
+		n++
+	}
+	return args[1:n], args[n:], true
+}
+
+// runMigrate выполняет команду migrate up|down|status|goto N|force N над встроенными миграциями
+func runMigrate(ctx context.Context, db *pgxpool.Pool, command []string, out io.Writer, logger *zap.Logger) error {
+	loaded, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+	migrator := migrate.New(db, loaded, logger)
+
+	switch {
+	case len(command) == 1 && command[0] == "up":
+		return migrator.Up(ctx)
+	case len(command) == 1 && command[0] == "down":
+		return migrator.Down(ctx)
+	case len(command) == 1 && command[0] == "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		return printMigrationStatus(out, statuses)
+	case len(command) == 2 && command[0] == "goto":
+		version, err := strconv.Atoi(command[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("migrate goto: version must be a non-negative number, got %q", command[1])
+		}
+		return migrator.Goto(ctx, version)
+	case len(command) == 2 && command[0] == "force":
+		version, err := strconv.Atoi(command[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("migrate force: version must be a non-negative number, got %q", command[1])
+		}
+		return migrator.Force(ctx, version)
+	default:
+		return errMigrateUsage
+	}
+}
+
+func printMigrationStatus(out io.Writer, statuses []migrate.Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		name := s.Name
+		if s.Up == "" {
+			name += " (not in this binary)"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, name, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qosmioo/merch-store/migrations"
+	"github.com/qosmioo/merch-store/pkg/migrate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitMigrateArgs(t *testing.T) {
+	command, configArgs, ok := splitMigrateArgs([]string{"migrate", "goto", "5", "--config", "cfg/config.yaml"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"goto", "5"}, command)
+	assert.Equal(t, []string{"--config", "cfg/config.yaml"}, configArgs)
+
+	command, configArgs, ok = splitMigrateArgs([]string{"--config", "cfg/config.yaml"})
+	assert.False(t, ok)
+	assert.Nil(t, command)
+	assert.Equal(t, []string{"--config", "cfg/config.yaml"}, configArgs)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := migrate.Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+	for i, migration := range loaded {
+		assert.Equal(t, i+1, migration.Version, "migration versions must be consecutive")
+	}
+}
+
+func TestPrintMigrationStatus(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, printMigrationStatus(&out, []migrate.Status{
+		{Migration: migrate.Migration{Version: 1, Name: "create_employees_table", Up: "-", Down: "-"}, Applied: true, AppliedAt: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{Migration: migrate.Migration{Version: 2, Name: "create_merch_table", Up: "-", Down: "-"}},
+		{Migration: migrate.Migration{Version: 3, Name: "from_newer_release"}, Applied: true, AppliedAt: time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)},
+	}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, []string{"VERSION", "NAME", "APPLIED", "AT"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"0001", "create_employees_table", "2025-03-01", "12:00:00"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"0002", "create_merch_table", "pending"}, strings.Fields(lines[2]))
+	assert.Contains(t, lines[3], "from_newer_release (not in this binary)")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/qosmioo/merch-store/cfg"
+	"github.com/qosmioo/merch-store/pkg/tlsreload"
+)
+
+// newServer собирает http.Server из конфигурации. Если заданы сертификат и
+// ключ, сервер работает по HTTPS, а сертификат перечитывается по SIGHUP и при
+// изменении файлов, пока не отменён ctx
+func newServer(ctx context.Context, config *cfg.Config, handler http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              ":" + strconv.Itoa(config.Server.Port),
+		Handler:           handler,
+		ReadTimeout:       config.Server.ReadTimeout,
+		ReadHeaderTimeout: config.Server.ReadHeaderTimeout,
+		WriteTimeout:      config.Server.WriteTimeout,
+		IdleTimeout:       config.Server.IdleTimeout,
+	}
+	if config.Server.TLS.CertFile == "" {
+		return server, nil
+	}
+
+	reloader, err := tlsreload.New(config.Server.TLS.CertFile, config.Server.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	onError := func(err error) {
+		log.Printf("Unable to reload TLS certificate, keeping the previous one: %v\n", err)
+	}
+	if config.Server.TLS.ReloadInterval > 0 {
+		go reloader.Watch(ctx, config.Server.TLS.ReloadInterval, onError)
+	}
+	go func() {
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		defer signal.Stop(hangup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				if err := reloader.Reload(); err != nil {
+					onError(err)
+					continue
+				}
+				log.Println("TLS certificate reloaded")
+			}
+		}
+	}()
+	return server, nil
+}
+
+// newMetricsServer собирает HTTP-сервер, который отдаёт только /metrics на
+// server.metrics_port, отдельно от публичного API
+func newMetricsServer(config *cfg.Config, metrics http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(config.Server.MetricsPort),
+		Handler:           mux,
+		ReadHeaderTimeout: config.Server.ReadHeaderTimeout,
+		WriteTimeout:      config.Server.WriteTimeout,
+		IdleTimeout:       config.Server.IdleTimeout,
+	}
+}
+
+// shutdownConfig описывает остановку сервера после сигнала
+type shutdownConfig struct {
+	// OnDrain вызывается сразу после сигнала, например чтобы /readyz начал отвечать 503
+	OnDrain func()
+	// DrainDelay — сколько сервер ещё принимает новые запросы после OnDrain
+	DrainDelay time.Duration
+	// Timeout ограничивает ожидание начатых запросов
+	Timeout time.Duration
+}
+
+// serve принимает запросы на listener до отмены ctx. Затем сообщает о
+// переходе в режим остановки, ждёт DrainDelay, перестаёт принимать новые
+// соединения и ждёт завершения начатых запросов не дольше Timeout
+func serve(ctx context.Context, server *http.Server, listener net.Listener, shutdown shutdownConfig) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			errCh <- server.ServeTLS(listener, "", "")
+		} else {
+			errCh <- server.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	if shutdown.OnDrain != nil {
+		shutdown.OnDrain()
+	}
+	if shutdown.DrainDelay > 0 {
+		log.Printf("Draining, accepting requests for %s more\n", shutdown.DrainDelay)
+		select {
+		case err := <-errCh:
+			return err
+		case <-time.After(shutdown.DrainDelay):
+		}
+	}
+
+	log.Printf("Shutting down, waiting up to %s for active requests\n", shutdown.Timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
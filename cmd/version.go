@@ -0,0 +1,44 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	httpHandler "github.com/qosmioo/merch-store/internal/delivery/http"
+)
+
+// Задаются при сборке, см. Dockerfile:
+// go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	version   = "dev"
+	commit    = ""
+	buildTime = ""
+)
+
+// buildInfo собирает сведения о сборке. Если commit и buildTime не заданы
+// через -ldflags, они берутся из данных VCS, которые go build встраивает сам
+func buildInfo() httpHandler.BuildInfo {
+	info := httpHandler.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+	}
+	if embedded, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range embedded.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInfo_UsesLdflags(t *testing.T) {
+	defer func(v, c, b string) { version, commit, buildTime = v, c, b }(version, commit, buildTime)
+	version, commit, buildTime = "1.2.0", "0123abc", "2025-03-01T12:00:00Z"
+
+	info := buildInfo()
+	assert.Equal(t, "1.2.0", info.Version)
+	assert.Equal(t, "0123abc", info.Commit)
+	assert.Equal(t, "2025-03-01T12:00:00Z", info.BuildTime)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+func (h *Handler) GetCart(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("GetCart called")
+	employeeID, ok := h.employeeIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+	cart, err := h.cartUsecase.GetCart(r.Context(), employeeID)
+	h.writeCart(w, cart, err)
+}
+
+// AddToCart принимает то же тело, что и POST /api/buy
+func (h *Handler) AddToCart(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("AddToCart called")
+	var request buyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+	if err := request.validate(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	employeeID, ok := h.employeeIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+	cart, err := h.cartUsecase.AddToCart(r.Context(), employeeID, request.Item, request.Quantity)
+	h.writeCart(w, cart, err)
+}
+
+func (h *Handler) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("RemoveFromCart called")
+	employeeID, ok := h.employeeIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+	cart, err := h.cartUsecase.RemoveFromCart(r.Context(), employeeID, mux.Vars(r)["item"])
+	h.writeCart(w, cart, err)
+}
+
+// Checkout оформляет корзину и возвращает купленные позиции с ценами и итоговой суммой
+func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Checkout called")
+	employeeID, ok := h.employeeIDFromClaims(w, r)
+	if !ok {
+		return
+	}
+	order, err := h.cartUsecase.Checkout(r.Context(), employeeID)
+	if err != nil {
+		h.logger.Error("Error checking out cart", zap.Error(err))
+		h.config.Metrics.insufficientCoinsFor(err, "checkout")
+	} else {
+		h.config.Metrics.purchased(order.Items...)
+	}
+	h.writeCart(w, order, err)
+}
+
+// employeeIDFromClaims возвращает ID сотрудника из токена запроса. Если
+// сотрудника определить не удалось, ответ с ошибкой уже записан
+func (h *Handler) employeeIDFromClaims(w http.ResponseWriter, r *http.Request) (int, bool) {
+	claims, ok := r.Context().Value("claims").(*jwt.Claims)
+	if !ok {
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
+		return 0, false
+	}
+	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
+	if err != nil {
+		h.logger.Error("Error getting employee ID", zap.Error(err))
+		h.writeError(w, err)
+		return 0, false
+	}
+	return employeeID, true
+}
+
+func (h *Handler) writeCart(w http.ResponseWriter, cart entity.Cart, err error) {
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cart)
+}
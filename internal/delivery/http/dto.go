@@ -0,0 +1,57 @@
+package http
+
+import (
+	"unicode/utf8"
+
+	"github.com/qosmioo/merch-store/internal/usecase"
+)
+
+const (
+	// maxTransferAmount ограничивает сумму одного перевода, чтобы опечатка
+	// в запросе не могла обнулить баланс целиком
+	maxTransferAmount = 100000
+	// maxUsernameLength совпадает с размером столбца employees.name
+	maxUsernameLength = 100
+)
+
+// sendCoinRequest — тело POST /api/sendCoin. Message и Public необязательны:
+// Public публикует перевод на стене благодарностей
+type sendCoinRequest struct {
+	ToUser  string `json:"toUser"`
+	Amount  int    `json:"amount"`
+	Message string `json:"message"`
+	Public  bool   `json:"public"`
+}
+
+// validate проверяет запрос перевода от имени sender
+func (r sendCoinRequest) validate(sender string) error {
+	switch {
+	case r.ToUser == "":
+		return &validationError{"toUser is required"}
+	case utf8.RuneCountInString(r.ToUser) > maxUsernameLength:
+		return &validationError{"toUser must be at most 100 characters long"}
+	case r.ToUser == sender:
+		return &validationError{"cannot transfer coins to yourself"}
+	case r.Amount <= 0:
+		return &validationError{"amount must be a positive integer"}
+	case r.Amount > maxTransferAmount:
+		return &validationError{"amount must not exceed 100000"}
+	}
+	return nil
+}
+
+// buyRequest — тело POST /api/buy
+type buyRequest struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+}
+
+func (r buyRequest) validate() error {
+	switch {
+	case r.Item == "":
+		return &validationError{"item is required"}
+	case r.Quantity <= 0 || r.Quantity > usecase.MaxPurchaseQuantity:
+		return &validationError{"quantity must be between 1 and 100"}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/qosmioo/merch-store/internal/usecase"
+	"go.uber.org/zap"
+)
+
+// ErrorResponse — тело ответа для любой ошибки API
+type ErrorResponse struct {
+	Errors string `json:"errors"`
+}
+
+// internalErrorMessage отдаётся клиенту вместо текста внутренних ошибок,
+// чтобы детали базы данных не попадали в ответ
+const internalErrorMessage = "internal server error"
+
+var errorKindStatus = map[usecase.ErrorKind]int{
+	usecase.KindInvalid:      http.StatusBadRequest,
+	usecase.KindUnauthorized: http.StatusUnauthorized,
+	usecase.KindNotFound:     http.StatusNotFound,
+	usecase.KindConflict:     http.StatusConflict,
+}
+
+// validationError — ошибка проверки тела запроса, всегда отдаётся с кодом 400
+type validationError struct {
+	message string
+}
+
+func (e *validationError) Error() string {
+	return e.message
+}
+
+// writeError преобразует ошибку в HTTP-ответ. Ошибки проверки запроса и
+// usecase.Error отдаются с их текстом, остальные логируются и превращаются в 500
+func (h *Handler) writeError(w http.ResponseWriter, err error) {
+	var invalidErr *validationError
+	if errors.As(err, &invalidErr) {
+		writeErrorMessage(w, http.StatusBadRequest, invalidErr.message)
+		return
+	}
+	var domainErr *usecase.Error
+	if errors.As(err, &domainErr) {
+		if status, ok := errorKindStatus[domainErr.Kind]; ok {
+			writeErrorMessage(w, status, domainErr.Message)
+			return
+		}
+	}
+	h.logger.Error("Internal error", zap.Error(err))
+	writeErrorMessage(w, http.StatusInternalServerError, internalErrorMessage)
+}
+
+func writeErrorMessage(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Errors: message})
+}
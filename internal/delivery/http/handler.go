@@ -2,49 +2,107 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/qosmioo/merch-store/internal/entity"
 	"github.com/qosmioo/merch-store/internal/usecase"
 	"github.com/qosmioo/merch-store/pkg/jwt"
 	"go.uber.org/zap"
 )
 
+// Config задаёт настраиваемое поведение HTTP API
+type Config struct {
+	// LegacyBuyGet включает устаревший маршрут GET /api/buy/{item}. Ответы
+	// на него помечаются заголовком Deprecation
+	LegacyBuyGet bool
+	// Metrics учитывает переводы, покупки и отказы. Если nil, метрики не собираются
+	Metrics *Metrics
+}
+
 type Handler struct {
-	employeeUsecase usecase.EmployeeUsecase
-	logger          *zap.Logger
+	employeeUsecase    usecase.EmployeeUsecase
+	authUsecase        usecase.AuthUsecase
+	merchUsecase       usecase.MerchUsecase
+	ledgerUsecase      usecase.LedgerUsecase
+	idempotencyUsecase usecase.IdempotencyUsecase
+	cartUsecase        usecase.CartUsecase
+	config             Config
+	logger             *zap.Logger
 }
 
-func NewHandler(employeeUsecase usecase.EmployeeUsecase, logger *zap.Logger) *Handler {
-	return &Handler{employeeUsecase: employeeUsecase, logger: logger}
+func NewHandler(employeeUsecase usecase.EmployeeUsecase, authUsecase usecase.AuthUsecase, merchUsecase usecase.MerchUsecase, ledgerUsecase usecase.LedgerUsecase, idempotencyUsecase usecase.IdempotencyUsecase, cartUsecase usecase.CartUsecase, config Config, logger *zap.Logger) *Handler {
+	return &Handler{
+		employeeUsecase:    employeeUsecase,
+		authUsecase:        authUsecase,
+		merchUsecase:       merchUsecase,
+		ledgerUsecase:      ledgerUsecase,
+		idempotencyUsecase: idempotencyUsecase,
+		cartUsecase:        cartUsecase,
+		config:             config,
+		logger:             logger,
+	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
-	router.HandleFunc("/api/info", jwt.AuthMiddleware(h.GetInfo)).Methods("GET")
-	router.HandleFunc("/api/sendCoin", jwt.AuthMiddleware(h.SendCoin)).Methods("POST")
-	router.HandleFunc("/api/buy/{item}", jwt.AuthMiddleware(h.BuyItem)).Methods("GET")
+	router.HandleFunc("/api/info", jwt.AuthMiddleware(h.authUsecase, h.GetInfo)).Methods("GET")
+	router.HandleFunc("/api/history", jwt.AuthMiddleware(h.authUsecase, h.GetHistory)).Methods("GET")
+	router.HandleFunc("/api/kudos", jwt.AuthMiddleware(h.authUsecase, h.ListKudos)).Methods("GET")
+	router.HandleFunc("/api/sendCoin", jwt.AuthMiddleware(h.authUsecase, h.withIdempotency(h.SendCoin))).Methods("POST")
+	router.HandleFunc("/api/buy", jwt.AuthMiddleware(h.authUsecase, h.withIdempotency(h.Buy))).Methods("POST")
+	router.HandleFunc("/api/cart", jwt.AuthMiddleware(h.authUsecase, h.GetCart)).Methods("GET")
+	router.HandleFunc("/api/cart/items", jwt.AuthMiddleware(h.authUsecase, h.AddToCart)).Methods("POST")
+	router.HandleFunc("/api/cart/items/{item}", jwt.AuthMiddleware(h.authUsecase, h.RemoveFromCart)).Methods("DELETE")
+	router.HandleFunc("/api/cart/checkout", jwt.AuthMiddleware(h.authUsecase, h.withIdempotency(h.Checkout))).Methods("POST")
+	if h.config.LegacyBuyGet {
+		router.HandleFunc("/api/buy/{item}", jwt.AuthMiddleware(h.authUsecase, h.withIdempotency(h.BuyItem))).Methods("GET")
+	}
+	router.HandleFunc("/api/merch", h.ListCatalog).Methods("GET")
 	router.HandleFunc("/api/auth", h.Authenticate).Methods("POST")
+	router.HandleFunc("/api/register", h.Register).Methods("POST")
+	router.HandleFunc("/api/auth/refresh", h.Refresh).Methods("POST")
+	router.HandleFunc("/api/auth/logout", jwt.AuthMiddleware(h.authUsecase, h.Logout)).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", h.JWKS).Methods("GET")
+
+	router.HandleFunc("/api/admin/employees/{username}", h.withRoles(h.GetEmployee, entity.RoleAdmin, entity.RoleAuditor)).Methods("GET")
+	router.HandleFunc("/api/admin/employees/{username}/role", h.withRoles(h.SetEmployeeRole, entity.RoleAdmin)).Methods("PUT")
+	router.HandleFunc("/api/admin/merch", h.withRoles(h.ListMerchAdmin, entity.RoleAdmin, entity.RoleAuditor)).Methods("GET")
+	router.HandleFunc("/api/admin/merch", h.withRoles(h.CreateMerch, entity.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/api/admin/merch/{id:[0-9]+}", h.withRoles(h.GetMerchAdmin, entity.RoleAdmin, entity.RoleAuditor)).Methods("GET")
+	router.HandleFunc("/api/admin/merch/{id:[0-9]+}", h.withRoles(h.UpdateMerch, entity.RoleAdmin)).Methods("PUT")
+	router.HandleFunc("/api/admin/merch/{id:[0-9]+}", h.withRoles(h.DeleteMerch, entity.RoleAdmin)).Methods("DELETE")
+	router.HandleFunc("/api/admin/merch/{id:[0-9]+}/restock", h.withRoles(h.withIdempotency(h.RestockMerch), entity.RoleAdmin)).Methods("POST")
+	router.HandleFunc("/api/admin/merch/{id:[0-9]+}/stock", h.withRoles(h.GetStockReport, entity.RoleAdmin, entity.RoleAuditor)).Methods("GET")
+	router.HandleFunc("/api/admin/ledger/check", h.withRoles(h.CheckLedger, entity.RoleAdmin, entity.RoleAuditor)).Methods("GET")
+}
+
+// withRoles проверяет токен и пропускает только сотрудников с одной из ролей
+func (h *Handler) withRoles(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return jwt.AuthMiddleware(h.authUsecase, jwt.RequireRole(next, roles...))
 }
 
 func (h *Handler) GetInfo(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("GetInfo called")
 	claims, ok := r.Context().Value("claims").(*jwt.Claims)
 	if !ok {
-		http.Error(w, "Неавторизован", http.StatusUnauthorized)
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
 		return
 	}
 
-	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(claims.Username)
+	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
 	if err != nil {
 		h.logger.Error("Error getting employee ID", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, err)
 		return
 	}
 
-	info, err := h.employeeUsecase.GetEmployeeInfo(employeeID)
+	info, err := h.employeeUsecase.GetEmployeeInfo(r.Context(), employeeID)
 	if err != nil {
 		h.logger.Error("Error getting employee info", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -52,74 +110,179 @@ func (h *Handler) GetInfo(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Successfully retrieved employee info", zap.Any("info", info))
 }
 
+// GetHistory отдаёт историю переводов постранично. Параметры: direction
+// (sent или received), from и to в формате RFC 3339, limit и cursor из
+// nextCursor предыдущей страницы
+func (h *Handler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("GetHistory called")
+	claims, ok := r.Context().Value("claims").(*jwt.Claims)
+	if !ok {
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := entity.HistoryFilter{Direction: query.Get("direction")}
+	for name, value := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := query.Get(name); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeErrorMessage(w, http.StatusBadRequest, name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*value = parsed
+		}
+	}
+	limit := 0
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+		limit = parsed
+	}
+
+	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
+	if err != nil {
+		h.logger.Error("Error getting employee ID", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	page, err := h.employeeUsecase.GetCoinHistory(r.Context(), employeeID, filter, query.Get("cursor"), limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// ListKudos отдаёт стену благодарностей: последние переводы, которые
+// отправители разрешили публиковать
+func (h *Handler) ListKudos(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ListKudos called")
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, "limit must be an integer")
+			return
+		}
+		limit = parsed
+	}
+
+	kudos, err := h.employeeUsecase.ListKudos(r.Context(), limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(kudos)
+}
+
 func (h *Handler) SendCoin(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("SendCoin called")
-	var request struct {
-		ToUser string `json:"toUser"`
-		Amount int    `json:"amount"`
-	}
+	var request sendCoinRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 	defer r.Body.Close()
 
 	claims, ok := r.Context().Value("claims").(*jwt.Claims)
 	if !ok {
-		http.Error(w, "Неавторизован", http.StatusUnauthorized)
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+	if err := request.validate(claims.Username); err != nil {
+		h.writeError(w, err)
 		return
 	}
 
-	fromEmployeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(claims.Username)
+	fromEmployeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
 	if err != nil {
 		h.logger.Error("Error getting sender employee ID", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, err)
 		return
 	}
 
-	toEmployeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(request.ToUser)
+	toEmployeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), request.ToUser)
+	if errors.Is(err, usecase.ErrEmployeeNotFound) {
+		err = usecase.ErrRecipientNotFound
+	}
 	if err != nil {
-		h.logger.Error("Recipient not found", zap.Error(err))
-		http.Error(w, "Получатель не найден", http.StatusBadRequest)
+		h.logger.Error("Error getting recipient employee ID", zap.Error(err))
+		h.writeError(w, err)
 		return
 	}
 
-	err = h.employeeUsecase.TransferCoins(fromEmployeeID, toEmployeeID, request.Amount)
+	err = h.employeeUsecase.TransferCoins(r.Context(), fromEmployeeID, toEmployeeID, request.Amount, entity.Memo{
+		Message: request.Message,
+		Public:  request.Public,
+	})
 	if err != nil {
 		h.logger.Error("Error transferring coins", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.config.Metrics.insufficientCoinsFor(err, "transfer")
+		h.writeError(w, err)
 		return
 	}
+	h.config.Metrics.transferred(request.Amount)
 	w.WriteHeader(http.StatusOK)
 	h.logger.Info("Successfully transferred coins", zap.String("from", claims.Username), zap.String("to", request.ToUser), zap.Int("amount", request.Amount))
 }
 
+// Buy покупает указанное количество единиц товара
+func (h *Handler) Buy(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Buy called")
+	var request buyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+	if err := request.validate(); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	h.buy(w, r, request.Item, request.Quantity)
+}
+
+// BuyItem — устаревший GET /api/buy/{item}, покупающий одну единицу товара.
+// GET не должен менять состояние: его могут выполнить предзагрузка ссылок и
+// поисковые роботы, поэтому вместо него следует использовать POST /api/buy
 func (h *Handler) BuyItem(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("BuyItem called")
-	vars := mux.Vars(r)
-	itemName := vars["item"]
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", `</api/buy>; rel="successor-version"`)
+	h.buy(w, r, mux.Vars(r)["item"], 1)
+}
 
+func (h *Handler) buy(w http.ResponseWriter, r *http.Request, itemName string, quantity int) {
 	claims, ok := r.Context().Value("claims").(*jwt.Claims)
 	if !ok {
-		http.Error(w, "Неавторизован", http.StatusUnauthorized)
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
 		return
 	}
 
-	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(claims.Username)
+	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
 	if err != nil {
 		h.logger.Error("Error getting employee ID", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, err)
 		return
 	}
 
-	err = h.employeeUsecase.BuyMerch(employeeID, itemName)
+	err = h.employeeUsecase.BuyMerch(r.Context(), employeeID, itemName, quantity)
 	if err != nil {
 		h.logger.Error("Error buying item", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.config.Metrics.insufficientCoinsFor(err, "purchase")
+		h.writeError(w, err)
 		return
 	}
+	h.config.Metrics.purchased(entity.CartItem{Item: itemName, Quantity: quantity})
 	w.WriteHeader(http.StatusOK)
-	h.logger.Info("Successfully purchased item", zap.String("item", itemName), zap.String("by", claims.Username))
+	h.logger.Info("Successfully purchased item", zap.String("item", itemName), zap.Int("quantity", quantity), zap.String("by", claims.Username))
 }
 
 func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) {
@@ -129,23 +292,147 @@ func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 	defer r.Body.Close()
 
-	token, err := h.employeeUsecase.Authenticate(request.Username, request.Password)
+	tokens, err := h.employeeUsecase.Authenticate(r.Context(), request.Username, request.Password)
 	if err != nil {
 		h.logger.Error("Authentication failed", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		h.config.Metrics.authFailed(err, "auth")
+		h.writeError(w, err)
 		return
 	}
 
-	response := struct {
-		Token string `json:"token"`
-	}{Token: token}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(tokens)
 	h.logger.Info("Successfully authenticated user", zap.String("username", request.Username))
 }
+
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Register called")
+	var request struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+
+	tokens, err := h.employeeUsecase.Register(r.Context(), request.Username, request.Password)
+	if err != nil {
+		h.logger.Error("Registration failed", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tokens)
+	h.logger.Info("Successfully registered user", zap.String("username", request.Username))
+}
+
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Refresh called")
+	var request struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.RefreshToken == "" {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+
+	tokens, err := h.authUsecase.Refresh(r.Context(), request.RefreshToken)
+	if err != nil {
+		h.logger.Error("Token refresh failed", zap.Error(err))
+		h.config.Metrics.authFailed(err, "refresh")
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Logout called")
+	claims, ok := r.Context().Value("claims").(*jwt.Claims)
+	if !ok {
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+
+	// Тело необязательно: без refresh-токена отзываются только access-токены
+	var request struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+	}
+	defer r.Body.Close()
+
+	err := h.authUsecase.Logout(r.Context(), claims.Username, request.RefreshToken)
+	if err != nil {
+		h.logger.Error("Logout failed", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Successfully logged out", zap.String("username", claims.Username))
+}
+
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.authUsecase.JWKS())
+}
+
+func (h *Handler) GetEmployee(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("GetEmployee called")
+	username := mux.Vars(r)["username"]
+
+	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), username)
+	if err != nil {
+		h.logger.Error("Error getting employee ID", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	info, err := h.employeeUsecase.GetEmployeeInfo(r.Context(), employeeID)
+	if err != nil {
+		h.logger.Error("Error getting employee info", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+func (h *Handler) SetEmployeeRole(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("SetEmployeeRole called")
+	username := mux.Vars(r)["username"]
+	var request struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+
+	err := h.employeeUsecase.SetEmployeeRole(r.Context(), username, request.Role)
+	if err != nil {
+		h.logger.Error("Error setting employee role", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Successfully updated employee role", zap.String("username", username), zap.String("role", request.Role))
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// readinessTimeout ограничивает время всех проверок одного запроса /readyz
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck — зависимость, без которой сервис не может обслуживать
+// запросы, например база данных
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// BuildInfo описывает сборку. Commit и BuildTime задаются при сборке через -ldflags
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthHandler отдаёт эндпоинты для оркестратора. Они не требуют
+// авторизации и не пишут в лог каждый вызов, так как опрашиваются постоянно
+type HealthHandler struct {
+	checks   []ReadinessCheck
+	build    BuildInfo
+	draining atomic.Bool
+	logger   *zap.Logger
+}
+
+func NewHealthHandler(build BuildInfo, checks []ReadinessCheck, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{checks: checks, build: build, logger: logger}
+}
+
+func (h *HealthHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/healthz", h.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", h.Readiness).Methods("GET")
+	router.HandleFunc("/version", h.Version).Methods("GET")
+}
+
+// SetDraining переводит /readyz в состояние 503 до конца работы процесса.
+// Вызывается при получении сигнала остановки, чтобы балансировщик перестал
+// направлять новые запросы
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness отвечает 200, пока процесс способен обрабатывать HTTP-запросы
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// Readiness выполняет все проверки и отвечает 503, если хотя бы одна не
+// прошла или сервис останавливается. Текст ошибок пишется только в лог
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		writeHealth(w, http.StatusServiceUnavailable, healthResponse{Status: "draining"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	response := healthResponse{Status: "ready", Checks: make(map[string]string, len(h.checks))}
+	status := http.StatusOK
+	for _, check := range h.checks {
+		if err := check.Check(ctx); err != nil {
+			h.logger.Warn("Readiness check failed", zap.String("check", check.Name), zap.Error(err))
+			response.Checks[check.Name] = "failed"
+			response.Status = "not ready"
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		response.Checks[check.Name] = "ok"
+	}
+	writeHealth(w, status, response)
+}
+
+func (h *HealthHandler) Version(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.build)
+}
+
+func writeHealth(w http.ResponseWriter, status int, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// maxIdempotentBodySize ограничивает тело запроса, которое читается целиком для
+// отпечатка. Более длинное тело отклоняется, а не обрезается: иначе запросы,
+// различающиеся только за пределом, получили бы одинаковый отпечаток
+const maxIdempotentBodySize = 1 << 20
+
+// responseRecorder передаёт ответ клиенту и одновременно запоминает его
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// withIdempotency выполняет запрос с заголовком Idempotency-Key не больше одного
+// раза: повтор получает сохранённый ответ с заголовком Idempotent-Replayed.
+// Ответы 5xx не сохраняются, чтобы запрос можно было повторить. Должен
+// вызываться после AuthMiddleware
+func (h *Handler) withIdempotency(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		claims, ok := r.Context().Value("claims").(*jwt.Claims)
+		if !ok {
+			writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodySize+1))
+		if err != nil {
+			writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		if len(body) > maxIdempotentBodySize {
+			writeErrorMessage(w, http.StatusRequestEntityTooLarge, "Request body too large")
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(append([]byte(r.Method+" "+r.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		replay, err := h.idempotencyUsecase.Begin(r.Context(), employeeID, key, fingerprint)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		if replay != nil {
+			if replay.ContentType != "" {
+				w.Header().Set("Content-Type", replay.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(replay.Status)
+			w.Write(replay.Body)
+			return
+		}
+
+		// Ответ сохраняется, даже если клиент уже отключился: именно он и будет повторять запрос
+		ctx := context.WithoutCancel(r.Context())
+		// Ключ освобождается и при панике в обработчике, иначе повторы
+		// получали бы 409 до конца аренды
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.idempotencyUsecase.Release(ctx, employeeID, key); err != nil {
+				h.logger.Error("Error releasing idempotency key", zap.Error(err))
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: w}
+		next(recorder, r)
+
+		if recorder.status == 0 || recorder.status >= http.StatusInternalServerError {
+			return
+		}
+		// Операция уже выполнена, поэтому ключ не освобождается, даже если ответ
+		// не удалось сохранить: до конца аренды повтор получит 409, а не выполнит её второй раз
+		completed = true
+		err = h.idempotencyUsecase.Complete(ctx, employeeID, key, entity.IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      recorder.status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err != nil {
+			h.logger.Error("Error storing idempotent response", zap.Error(err))
+		}
+	}
+}
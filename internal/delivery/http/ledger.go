@@ -0,0 +1,19 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// CheckLedger отдаёт результат проверки инвариантов журнала. Нарушение
+// инвариантов не считается ошибкой запроса: отчёт возвращается с conserved = false
+func (h *Handler) CheckLedger(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("CheckLedger called")
+	report, err := h.ledgerUsecase.CheckLedger(r.Context())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
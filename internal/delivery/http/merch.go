@@ -0,0 +1,240 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/pkg/jwt"
+	"go.uber.org/zap"
+)
+
+// ListCatalog отдаёт публичный каталог. Ответ снабжается ETag, по которому
+// клиент может получить 304 Not Modified вместо повторной загрузки каталога
+func (h *Handler) ListCatalog(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ListCatalog called")
+	query := r.URL.Query()
+	filter := entity.MerchFilter{
+		Category: query.Get("category"),
+		Sort:     query.Get("sort"),
+	}
+	for name, value := range map[string]*int{"minPrice": &filter.MinPrice, "maxPrice": &filter.MaxPrice} {
+		if raw := query.Get(name); raw != "" {
+			price, err := strconv.Atoi(raw)
+			if err != nil {
+				writeErrorMessage(w, http.StatusBadRequest, name+" must be an integer")
+				return
+			}
+			*value = price
+		}
+	}
+
+	catalog, err := h.merchUsecase.ListCatalog(r.Context(), filter)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	body, err := json.Marshal(catalog)
+	if err != nil {
+		h.logger.Error("Error encoding catalog", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	// no-cache разрешает хранить ответ, но требует сверять ETag при каждом запросе:
+	// остатки и цены могут измениться в любой момент
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// etagMatches проверяет заголовок If-None-Match, который может содержать
+// список тегов, слабые теги W/"..." и "*"
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) ListMerchAdmin(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("ListMerchAdmin called")
+	includeDeleted := r.URL.Query().Get("includeDeleted") == "true"
+
+	merch, err := h.merchUsecase.ListMerch(r.Context(), includeDeleted)
+	if err != nil {
+		h.logger.Error("Error listing merch", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merch)
+}
+
+func (h *Handler) GetMerchAdmin(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("GetMerchAdmin called")
+	merchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	merch, err := h.merchUsecase.GetMerch(r.Context(), merchID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merch)
+}
+
+func (h *Handler) CreateMerch(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("CreateMerch called")
+	var request struct {
+		Name        string `json:"name"`
+		Price       int    `json:"price"`
+		Description string `json:"description"`
+		Category    string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+
+	merch, err := h.merchUsecase.CreateMerch(r.Context(), entity.Merch{
+		Name:        request.Name,
+		Price:       request.Price,
+		Description: request.Description,
+		Category:    request.Category,
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(merch)
+	h.logger.Info("Successfully created merch", zap.String("name", merch.Name))
+}
+
+func (h *Handler) UpdateMerch(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("UpdateMerch called")
+	merchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	var request struct {
+		Price       int    `json:"price"`
+		Description string `json:"description"`
+		Category    string `json:"category"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+
+	merch, err := h.merchUsecase.UpdateMerch(r.Context(), entity.Merch{
+		ID:          merchID,
+		Price:       request.Price,
+		Description: request.Description,
+		Category:    request.Category,
+	})
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merch)
+	h.logger.Info("Successfully updated merch", zap.Int("merchID", merchID))
+}
+
+func (h *Handler) DeleteMerch(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("DeleteMerch called")
+	merchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	if err := h.merchUsecase.DeleteMerch(r.Context(), merchID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+	h.logger.Info("Successfully deleted merch", zap.Int("merchID", merchID))
+}
+
+func (h *Handler) RestockMerch(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("RestockMerch called")
+	merchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	var request struct {
+		Quantity int    `json:"quantity"`
+		Comment  string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	defer r.Body.Close()
+
+	claims, ok := r.Context().Value("claims").(*jwt.Claims)
+	if !ok {
+		writeErrorMessage(w, http.StatusUnauthorized, "Неавторизован")
+		return
+	}
+	employeeID, err := h.employeeUsecase.GetEmployeeIDByUsername(r.Context(), claims.Username)
+	if err != nil {
+		h.logger.Error("Error getting employee ID", zap.Error(err))
+		h.writeError(w, err)
+		return
+	}
+
+	merch, err := h.merchUsecase.RestockMerch(r.Context(), merchID, request.Quantity, employeeID, request.Comment)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(merch)
+	h.logger.Info("Successfully restocked merch", zap.Int("merchID", merchID), zap.Int("quantity", request.Quantity), zap.String("by", claims.Username))
+}
+
+func (h *Handler) GetStockReport(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("GetStockReport called")
+	merchID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		writeErrorMessage(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	report, err := h.merchUsecase.GetStockReport(r.Context(), merchID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/usecase"
+)
+
+// Metrics — длительность HTTP-запросов и бизнес-события. Бизнес-события
+// учитываются только для выполненных запросов: ответы, повторённые по
+// Idempotency-Key, не считаются повторно. Nil-значение ничего не записывает
+type Metrics struct {
+	requestDuration   *prometheus.HistogramVec
+	coinsTransferred  prometheus.Counter
+	itemsPurchased    *prometheus.CounterVec
+	authFailures      *prometheus.CounterVec
+	insufficientCoins *prometheus.CounterVec
+}
+
+// NewMetrics регистрирует метрики в registerer. Повторная регистрация в том
+// же registerer вызывает панику
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	factory := promauto.With(registerer)
+	return &Metrics{
+		requestDuration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests by route template, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		coinsTransferred: factory.NewCounter(prometheus.CounterOpts{
+			Name: "merch_store_coins_transferred_total",
+			Help: "Coins transferred between employees.",
+		}),
+		itemsPurchased: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "merch_store_items_purchased_total",
+			Help: "Purchased units by item, including cart checkouts.",
+		}, []string{"item"}),
+		authFailures: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "merch_store_auth_failures_total",
+			Help: "Rejected logins and refresh token exchanges.",
+		}, []string{"endpoint"}),
+		insufficientCoins: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "merch_store_insufficient_coins_total",
+			Help: "Operations rejected because the employee did not have enough coins.",
+		}, []string{"operation"}),
+	}
+}
+
+// Middleware измеряет длительность запросов. Метка route — шаблон маршрута
+// mux, а не путь, чтобы /api/admin/merch/1 и /api/admin/merch/2 попадали в одну серию
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		started := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+		m.requestDuration.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Observe(time.Since(started).Seconds())
+	})
+}
+
+func (m *Metrics) transferred(amount int) {
+	if m != nil {
+		m.coinsTransferred.Add(float64(amount))
+	}
+}
+
+func (m *Metrics) purchased(lines ...entity.CartItem) {
+	if m == nil {
+		return
+	}
+	for _, line := range lines {
+		m.itemsPurchased.WithLabelValues(line.Item).Add(float64(line.Quantity))
+	}
+}
+
+// authFailed учитывает отказ во входе или обмене refresh-токена. Остальные
+// ошибки видны в http_request_duration_seconds по коду ответа
+func (m *Metrics) authFailed(err error, endpoint string) {
+	if m != nil && (errors.Is(err, usecase.ErrInvalidCredentials) || errors.Is(err, usecase.ErrInvalidRefreshToken)) {
+		m.authFailures.WithLabelValues(endpoint).Inc()
+	}
+}
+
+// insufficientCoinsFor учитывает операции, отклонённые из-за нехватки монет
+func (m *Metrics) insufficientCoinsFor(err error, operation string) {
+	if m != nil && errors.Is(err, usecase.ErrInsufficientCoins) {
+		m.insufficientCoins.WithLabelValues(operation).Inc()
+	}
+}
+
+// statusRecorder запоминает код ответа
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(body []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(body)
+}
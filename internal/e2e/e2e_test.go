@@ -2,17 +2,29 @@ package e2e
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	handler "github.com/qosmioo/merch-store/internal/delivery/http"
 	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/usecase"
 	"github.com/qosmioo/merch-store/pkg/jwt"
+	"github.com/qosmioo/merch-store/pkg/utils"
 	"go.uber.org/zap"
 )
 
@@ -20,9 +32,114 @@ type EmployeeData struct {
 	ID          int
 	Name        string
 	Password    string
+	Role        string
 	Coins       int
 	Inventory   []entity.Inventory
 	CoinHistory entity.CoinHistory
+	Purchases   []entity.Purchase
+}
+
+type fakeRefreshToken struct {
+	employeeID int
+	username   string
+	revoked    bool
+}
+
+type FakeAuthUsecase struct {
+	mu            sync.Mutex
+	tokens        *jwt.Manager
+	versions      map[string]int
+	roles         map[string]string
+	refreshTokens map[string]*fakeRefreshToken
+}
+
+func NewFakeAuthUsecase() *FakeAuthUsecase {
+	tokens, err := jwt.NewManager(jwt.Config{
+		Issuer:       "merch-store",
+		Audience:     "merch-store",
+		TTL:          time.Hour,
+		SigningKeyID: "test",
+		Keys:         []jwt.KeyConfig{{ID: "test", Algorithm: "HS256", Secret: "test-secret-that-is-at-least-32-bytes"}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return &FakeAuthUsecase{
+		tokens:        tokens,
+		versions:      make(map[string]int),
+		roles:         make(map[string]string),
+		refreshTokens: make(map[string]*fakeRefreshToken),
+	}
+}
+
+func (a *FakeAuthUsecase) IssueTokens(ctx context.Context, employeeID int, username string) (entity.TokenPair, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.issueTokens(employeeID, username)
+}
+
+func (a *FakeAuthUsecase) issueTokens(employeeID int, username string) (entity.TokenPair, error) {
+	accessToken, err := a.tokens.Sign(&jwt.Claims{Username: username, Role: a.role(username), TokenVersion: a.versions[username]})
+	if err != nil {
+		return entity.TokenPair{}, err
+	}
+	refreshToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		return entity.TokenPair{}, err
+	}
+	a.refreshTokens[refreshToken] = &fakeRefreshToken{employeeID: employeeID, username: username}
+	return entity.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (a *FakeAuthUsecase) Refresh(ctx context.Context, refreshToken string) (entity.TokenPair, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	token, ok := a.refreshTokens[refreshToken]
+	if !ok || token.revoked {
+		return entity.TokenPair{}, usecase.ErrInvalidRefreshToken
+	}
+	token.revoked = true
+	return a.issueTokens(token.employeeID, token.username)
+}
+
+func (a *FakeAuthUsecase) Logout(ctx context.Context, username, refreshToken string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if token, ok := a.refreshTokens[refreshToken]; ok && token.username == username {
+		token.revoked = true
+	}
+	a.versions[username]++
+	return nil
+}
+
+func (a *FakeAuthUsecase) Verify(ctx context.Context, tokenStr string) (*jwt.Claims, error) {
+	claims, err := a.tokens.Verify(ctx, tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if claims.TokenVersion != a.versions[claims.Username] || claims.Role != a.role(claims.Username) {
+		return nil, jwt.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (a *FakeAuthUsecase) role(username string) string {
+	if role, ok := a.roles[username]; ok {
+		return role
+	}
+	return entity.RoleEmployee
+}
+
+func (a *FakeAuthUsecase) setRole(username, role string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.roles[username] = role
+}
+
+func (a *FakeAuthUsecase) JWKS() jwt.JWKS {
+	return a.tokens.JWKS()
 }
 
 type FakeEmployeeUsecase struct {
@@ -30,6 +147,26 @@ type FakeEmployeeUsecase struct {
 	nextID              int
 	employeesByUsername map[string]*EmployeeData
 	employeesByID       map[int]*EmployeeData
+	auth                *FakeAuthUsecase
+	merch               *FakeMerchUsecase
+	idempotency         *FakeIdempotencyUsecase
+	// buyErr, если задана, возвращается из BuyMerch вместо покупки
+	buyErr error
+	// issued и store — выпущенные монеты и выручка магазина для CheckLedger
+	issued int
+	store  int
+	// transfers — все переводы в порядке выполнения для GetCoinHistory
+	transfers []fakeTransfer
+	carts     map[int][]entity.CartItem
+}
+
+type fakeTransfer struct {
+	id        int
+	from      int
+	to        int
+	amount    int
+	memo      entity.Memo
+	createdAt time.Time
 }
 
 func NewFakeEmployeeUsecase() *FakeEmployeeUsecase {
@@ -37,123 +174,555 @@ func NewFakeEmployeeUsecase() *FakeEmployeeUsecase {
 		nextID:              1,
 		employeesByUsername: make(map[string]*EmployeeData),
 		employeesByID:       make(map[int]*EmployeeData),
+		auth:                NewFakeAuthUsecase(),
+		merch:               NewFakeMerchUsecase(),
+		idempotency:         NewFakeIdempotencyUsecase(),
+		carts:               make(map[int][]entity.CartItem),
 	}
 }
 
-func (f *FakeEmployeeUsecase) Authenticate(username, password string) (string, error) {
+func (f *FakeEmployeeUsecase) Authenticate(ctx context.Context, username, password string) (entity.TokenPair, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
 	emp, ok := f.employeesByUsername[username]
-	if ok {
-		if emp.Password != password {
-			return "", errors.New("invalid credentials")
-		}
-	} else {
-		emp = &EmployeeData{
-			ID:          f.nextID,
-			Name:        username,
-			Password:    password,
-			Coins:       1000,
-			Inventory:   []entity.Inventory{},
-			CoinHistory: entity.CoinHistory{},
-		}
-		f.employeesByUsername[username] = emp
-		f.employeesByID[f.nextID] = emp
-		f.nextID++
+	if !ok || emp.Password != password {
+		return entity.TokenPair{}, usecase.ErrInvalidCredentials
+	}
+	return f.auth.IssueTokens(ctx, emp.ID, username)
+}
+
+func (f *FakeEmployeeUsecase) Register(ctx context.Context, username, password string) (entity.TokenPair, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.employeesByUsername[username]; ok {
+		return entity.TokenPair{}, usecase.ErrUsernameTaken
+	}
+	emp := &EmployeeData{
+		ID:          f.nextID,
+		Name:        username,
+		Password:    password,
+		Role:        entity.RoleEmployee,
+		Coins:       1000,
+		Inventory:   []entity.Inventory{},
+		CoinHistory: entity.CoinHistory{},
+		Purchases:   []entity.Purchase{},
 	}
-	return jwt.GenerateJWT(username)
+	f.employeesByUsername[username] = emp
+	f.employeesByID[f.nextID] = emp
+	f.nextID++
+	f.issued += emp.Coins
+	return f.auth.IssueTokens(ctx, emp.ID, username)
 }
 
-func (f *FakeEmployeeUsecase) GetEmployeeIDByUsername(username string) (int, error) {
+func (f *FakeEmployeeUsecase) GetEmployeeIDByUsername(ctx context.Context, username string) (int, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	emp, ok := f.employeesByUsername[username]
 	if !ok {
-		return 0, errors.New("employee not found")
+		return 0, usecase.ErrEmployeeNotFound
 	}
 	return emp.ID, nil
 }
 
-func (f *FakeEmployeeUsecase) GetEmployeeInfo(employeeID int) (entity.InfoResponse, error) {
+func (f *FakeEmployeeUsecase) GetEmployeeInfo(ctx context.Context, employeeID int) (entity.InfoResponse, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	emp, ok := f.employeesByID[employeeID]
 	if !ok {
-		return entity.InfoResponse{}, errors.New("employee not found")
+		return entity.InfoResponse{}, usecase.ErrEmployeeNotFound
 	}
 	return entity.InfoResponse{
 		Coins:       emp.Coins,
 		Inventory:   emp.Inventory,
 		CoinHistory: emp.CoinHistory,
+		Purchases:   emp.Purchases,
 	}, nil
 }
 
-func (f *FakeEmployeeUsecase) TransferCoins(fromEmployeeID, toEmployeeID, amount int) error {
+func (f *FakeEmployeeUsecase) TransferCoins(ctx context.Context, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	fromEmp, ok := f.employeesByID[fromEmployeeID]
 	if !ok {
-		return errors.New("from employee not found")
+		return usecase.ErrEmployeeNotFound
 	}
 	toEmp, ok := f.employeesByID[toEmployeeID]
 	if !ok {
-		return errors.New("to employee not found")
+		return usecase.ErrEmployeeNotFound
+	}
+	if fromEmployeeID == toEmployeeID {
+		return usecase.ErrSelfTransfer
 	}
 	if fromEmp.Coins < amount {
-		return errors.New("insufficient coins")
+		return usecase.ErrInsufficientCoins
 	}
 	fromEmp.Coins -= amount
 	toEmp.Coins += amount
 
+	now := time.Now()
 	fromEmp.CoinHistory.Sent = append(fromEmp.CoinHistory.Sent, entity.Transaction{
-		UserID: toEmployeeID,
-		Amount: amount,
+		ToUser:    toEmp.Name,
+		Amount:    amount,
+		Message:   memo.Message,
+		CreatedAt: now,
 	})
 	toEmp.CoinHistory.Received = append(toEmp.CoinHistory.Received, entity.Transaction{
-		UserID: fromEmployeeID,
-		Amount: amount,
+		FromUser:  fromEmp.Name,
+		Amount:    amount,
+		Message:   memo.Message,
+		CreatedAt: now,
 	})
+	f.transfers = append(f.transfers, fakeTransfer{id: len(f.transfers) + 1, from: fromEmployeeID, to: toEmployeeID, amount: amount, memo: memo, createdAt: now})
 	return nil
 }
 
-func (f *FakeEmployeeUsecase) BuyMerch(employeeID int, itemName string) error {
+func (f *FakeEmployeeUsecase) ListKudos(ctx context.Context, limit int) ([]entity.Kudos, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if limit == 0 {
+		limit = usecase.DefaultHistoryLimit
+	}
+	if limit < 0 || limit > usecase.MaxHistoryLimit {
+		return nil, usecase.ErrInvalidKudosLimit
+	}
+	kudos := []entity.Kudos{}
+	for i := len(f.transfers) - 1; i >= 0 && len(kudos) < limit; i-- {
+		transfer := f.transfers[i]
+		if !transfer.memo.Public {
+			continue
+		}
+		kudos = append(kudos, entity.Kudos{
+			ID:        transfer.id,
+			FromUser:  f.employeesByID[transfer.from].Name,
+			ToUser:    f.employeesByID[transfer.to].Name,
+			Amount:    transfer.amount,
+			Message:   transfer.memo.Message,
+			CreatedAt: transfer.createdAt,
+		})
+	}
+	return kudos, nil
+}
+
+// GetCoinHistory использует в качестве курсора смещение в отфильтрованной истории
+func (f *FakeEmployeeUsecase) GetCoinHistory(ctx context.Context, employeeID int, filter entity.HistoryFilter, cursor string, limit int) (entity.HistoryPage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if limit == 0 {
+		limit = usecase.DefaultHistoryLimit
+	}
+	if limit < 0 || limit > usecase.MaxHistoryLimit {
+		return entity.HistoryPage{}, usecase.ErrInvalidHistory
+	}
+	offset := 0
+	if cursor != "" {
+		var err error
+		if offset, err = strconv.Atoi(cursor); err != nil {
+			return entity.HistoryPage{}, usecase.ErrInvalidCursor
+		}
+	}
+
+	entries := []entity.HistoryEntry{}
+	for i := len(f.transfers) - 1; i >= 0; i-- {
+		transfer := f.transfers[i]
+		entry := entity.HistoryEntry{
+			FromUser:  f.employeesByID[transfer.from].Name,
+			ToUser:    f.employeesByID[transfer.to].Name,
+			ID:        transfer.id,
+			Amount:    transfer.amount,
+			Message:   transfer.memo.Message,
+			Public:    transfer.memo.Public,
+			CreatedAt: transfer.createdAt,
+		}
+		switch {
+		case transfer.from == employeeID && filter.Direction != entity.DirectionReceived:
+			entry.Direction = entity.DirectionSent
+		case transfer.to == employeeID && filter.Direction != entity.DirectionSent:
+			entry.Direction = entity.DirectionReceived
+		default:
+			continue
+		}
+		if (!filter.From.IsZero() && entry.CreatedAt.Before(filter.From)) || (!filter.To.IsZero() && !entry.CreatedAt.Before(filter.To)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	page := entity.HistoryPage{Items: []entity.HistoryEntry{}}
+	if offset < len(entries) {
+		page.Items = entries[offset:]
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = strconv.Itoa(offset + limit)
+	}
+	return page, nil
+}
+
+func (f *FakeEmployeeUsecase) BuyMerch(ctx context.Context, employeeID int, itemName string, quantity int) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	if quantity <= 0 || quantity > usecase.MaxPurchaseQuantity {
+		return usecase.ErrInvalidQuantity
+	}
+	_, err := f.buy(employeeID, []entity.CartItem{{Item: itemName, Quantity: quantity}})
+	return err
+}
+
+// fakePrice — цена любого товара в фейке
+const fakePrice = 50
+
+// buy покупает все позиции или ни одной. Вызывается под f.mu
+func (f *FakeEmployeeUsecase) buy(employeeID int, lines []entity.CartItem) (entity.Cart, error) {
+	if f.buyErr != nil {
+		return entity.Cart{}, f.buyErr
+	}
 	emp, ok := f.employeesByID[employeeID]
 	if !ok {
-		return errors.New("employee not found")
-	}
-	price := 50
-	if emp.Coins < price {
-		return errors.New("insufficient coins")
-	}
-	emp.Coins -= price
-	updated := false
-	for i, item := range emp.Inventory {
-		if item.Type == itemName {
-			emp.Inventory[i].Quantity++
-			updated = true
-			break
+		return entity.Cart{}, usecase.ErrEmployeeNotFound
+	}
+	order := entity.Cart{}
+	for _, line := range lines {
+		line.Price = fakePrice
+		order.Items = append(order.Items, line)
+		order.Total += fakePrice * line.Quantity
+	}
+	if emp.Coins < order.Total {
+		return entity.Cart{}, usecase.ErrInsufficientCoins
+	}
+	if err := f.merch.takeStock(lines); err != nil {
+		return entity.Cart{}, err
+	}
+	emp.Coins -= order.Total
+	f.store += order.Total
+	for _, line := range order.Items {
+		emp.Purchases = append([]entity.Purchase{{Item: line.Item, Price: line.Price, Quantity: line.Quantity, CreatedAt: time.Now()}}, emp.Purchases...)
+		updated := false
+		for i, item := range emp.Inventory {
+			if item.Type == line.Item {
+				emp.Inventory[i].Quantity += line.Quantity
+				updated = true
+				break
+			}
+		}
+		if !updated {
+			emp.Inventory = append(emp.Inventory, entity.Inventory{
+				Type:     line.Item,
+				Quantity: line.Quantity,
+			})
 		}
 	}
-	if !updated {
-		emp.Inventory = append(emp.Inventory, entity.Inventory{
-			Type:     itemName,
-			Quantity: 1,
-		})
+	return order, nil
+}
+
+func (f *FakeEmployeeUsecase) GetCart(ctx context.Context, employeeID int) (entity.Cart, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cart(employeeID), nil
+}
+
+func (f *FakeEmployeeUsecase) cart(employeeID int) entity.Cart {
+	cart := entity.Cart{Items: []entity.CartItem{}}
+	for _, line := range f.carts[employeeID] {
+		line.Price = fakePrice
+		cart.Items = append(cart.Items, line)
+		cart.Total += fakePrice * line.Quantity
+	}
+	return cart
+}
+
+func (f *FakeEmployeeUsecase) AddToCart(ctx context.Context, employeeID int, itemName string, quantity int) (entity.Cart, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines := f.carts[employeeID]
+	for i := range lines {
+		if lines[i].Item == itemName {
+			if lines[i].Quantity+quantity > usecase.MaxPurchaseQuantity {
+				return entity.Cart{}, usecase.ErrInvalidQuantity
+			}
+			lines[i].Quantity += quantity
+			return f.cart(employeeID), nil
+		}
+	}
+	lines = append(lines, entity.CartItem{Item: itemName, Quantity: quantity})
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Item < lines[j].Item })
+	f.carts[employeeID] = lines
+	return f.cart(employeeID), nil
+}
+
+func (f *FakeEmployeeUsecase) RemoveFromCart(ctx context.Context, employeeID int, itemName string) (entity.Cart, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines := f.carts[employeeID]
+	for i := range lines {
+		if lines[i].Item == itemName {
+			f.carts[employeeID] = append(lines[:i], lines[i+1:]...)
+			return f.cart(employeeID), nil
+		}
+	}
+	return entity.Cart{}, usecase.ErrNotInCart
+}
+
+func (f *FakeEmployeeUsecase) Checkout(ctx context.Context, employeeID int) (entity.Cart, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.carts[employeeID]) == 0 {
+		return entity.Cart{}, usecase.ErrEmptyCart
+	}
+	order, err := f.buy(employeeID, f.carts[employeeID])
+	if err != nil {
+		return entity.Cart{}, err
+	}
+	delete(f.carts, employeeID)
+	return order, nil
+}
+
+func (f *FakeEmployeeUsecase) SetEmployeeRole(ctx context.Context, username, role string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !entity.IsValidRole(role) {
+		return usecase.ErrInvalidRole
+	}
+	emp, ok := f.employeesByUsername[username]
+	if !ok {
+		return usecase.ErrEmployeeNotFound
+	}
+	emp.Role = role
+	f.auth.setRole(username, role)
+	return nil
+}
+
+func (f *FakeEmployeeUsecase) CheckLedger(ctx context.Context) (entity.LedgerReport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	report := entity.LedgerReport{
+		Issued:            f.issued,
+		Store:             f.store,
+		UnbalancedEntries: []int{},
+		BalanceMismatches: []entity.BalanceMismatch{},
+	}
+	for _, emp := range f.employeesByID {
+		report.Employees += emp.Coins
+	}
+	report.Conserved = report.Issued == report.Employees+report.Store
+	return report, nil
+}
+
+type FakeMerchUsecase struct {
+	mu        sync.Mutex
+	nextID    int
+	items     map[int]*entity.Merch
+	movements map[int][]entity.StockMovement
+}
+
+func NewFakeMerchUsecase() *FakeMerchUsecase {
+	return &FakeMerchUsecase{
+		nextID:    1,
+		items:     make(map[int]*entity.Merch),
+		movements: make(map[int][]entity.StockMovement),
+	}
+}
+
+func (m *FakeMerchUsecase) ListCatalog(ctx context.Context, filter entity.MerchFilter) ([]entity.Merch, error) {
+	if filter.MinPrice < 0 || filter.MaxPrice < 0 {
+		return nil, usecase.ErrInvalidMerchFilter
+	}
+	merch, _ := m.ListMerch(ctx, false)
+	catalog := []entity.Merch{}
+	for _, item := range merch {
+		if item.Price >= filter.MinPrice && (filter.MaxPrice == 0 || item.Price <= filter.MaxPrice) {
+			catalog = append(catalog, item)
+		}
+	}
+	return catalog, nil
+}
+
+func (m *FakeMerchUsecase) ListMerch(ctx context.Context, includeDeleted bool) ([]entity.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	merch := []entity.Merch{}
+	for id := 1; id < m.nextID; id++ {
+		if item := m.items[id]; includeDeleted || item.DeletedAt == nil {
+			merch = append(merch, *item)
+		}
+	}
+	return merch, nil
+}
+
+func (m *FakeMerchUsecase) GetMerch(ctx context.Context, merchID int) (entity.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[merchID]
+	if !ok {
+		return entity.Merch{}, usecase.ErrItemNotFound
+	}
+	return *item, nil
+}
+
+func (m *FakeMerchUsecase) CreateMerch(ctx context.Context, merch entity.Merch) (entity.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if merch.Price <= 0 {
+		return entity.Merch{}, usecase.ErrInvalidPrice
+	}
+	for _, item := range m.items {
+		if item.Name == merch.Name && item.DeletedAt == nil {
+			return entity.Merch{}, usecase.ErrMerchExists
+		}
+	}
+	merch.ID = m.nextID
+	m.items[merch.ID] = &merch
+	m.nextID++
+	return merch, nil
+}
+
+func (m *FakeMerchUsecase) UpdateMerch(ctx context.Context, merch entity.Merch) (entity.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if merch.Price <= 0 {
+		return entity.Merch{}, usecase.ErrInvalidPrice
+	}
+	item, ok := m.items[merch.ID]
+	if !ok || item.DeletedAt != nil {
+		return entity.Merch{}, usecase.ErrItemNotFound
+	}
+	item.Price = merch.Price
+	return *item, nil
+}
+
+func (m *FakeMerchUsecase) DeleteMerch(ctx context.Context, merchID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[merchID]
+	if !ok || item.DeletedAt != nil {
+		return usecase.ErrItemNotFound
+	}
+	now := time.Now()
+	item.DeletedAt = &now
+	return nil
+}
+
+func (m *FakeMerchUsecase) RestockMerch(ctx context.Context, merchID, quantity, employeeID int, comment string) (entity.Merch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if quantity <= 0 {
+		return entity.Merch{}, usecase.ErrInvalidRestock
 	}
+	item, ok := m.items[merchID]
+	if !ok || item.DeletedAt != nil {
+		return entity.Merch{}, usecase.ErrItemNotFound
+	}
+	item.Stock += quantity
+	m.movements[merchID] = append(m.movements[merchID], entity.StockMovement{
+		ID:        len(m.movements[merchID]) + 1,
+		Quantity:  quantity,
+		Comment:   comment,
+		CreatedAt: time.Now(),
+	})
+	return *item, nil
+}
+
+func (m *FakeMerchUsecase) GetStockReport(ctx context.Context, merchID int) (entity.StockReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[merchID]
+	if !ok {
+		return entity.StockReport{}, usecase.ErrItemNotFound
+	}
+	report := entity.StockReport{MerchID: merchID, Stock: item.Stock, Movements: m.movements[merchID]}
+	for _, movement := range report.Movements {
+		report.Restocked += movement.Quantity
+	}
+	report.Sold = report.Restocked - report.Stock
+	return report, nil
+}
+
+// takeStock списывает товары позиций lines из каталога, только если их
+// хватает на все позиции. Товары, которых нет в каталоге, считаются
+// неограниченными, чтобы тесты покупок не зависели от него
+func (m *FakeMerchUsecase) takeStock(lines []entity.CartItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, line := range lines {
+		for _, item := range m.items {
+			if item.Name == line.Item && item.DeletedAt == nil && item.Stock < line.Quantity {
+				return usecase.ErrOutOfStock
+			}
+		}
+	}
+	for _, line := range lines {
+		for _, item := range m.items {
+			if item.Name == line.Item && item.DeletedAt == nil {
+				item.Stock -= line.Quantity
+			}
+		}
+	}
+	return nil
+}
+
+type fakeIdempotencyKey struct {
+	employeeID int
+	key        string
+}
+
+// FakeIdempotencyUsecase хранит ответы в памяти и никогда не истекает
+type FakeIdempotencyUsecase struct {
+	mu        sync.Mutex
+	responses map[fakeIdempotencyKey]*entity.IdempotentResponse
+}
+
+func NewFakeIdempotencyUsecase() *FakeIdempotencyUsecase {
+	return &FakeIdempotencyUsecase{responses: make(map[fakeIdempotencyKey]*entity.IdempotentResponse)}
+}
+
+func (i *FakeIdempotencyUsecase) Begin(ctx context.Context, employeeID int, key, fingerprint string) (*entity.IdempotentResponse, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	id := fakeIdempotencyKey{employeeID, key}
+	stored, ok := i.responses[id]
+	if !ok {
+		i.responses[id] = &entity.IdempotentResponse{Fingerprint: fingerprint}
+		return nil, nil
+	}
+	if stored.Fingerprint != fingerprint {
+		return nil, usecase.ErrIdempotencyKeyReused
+	}
+	if stored.Status == 0 {
+		return nil, usecase.ErrIdempotencyInProgress
+	}
+	response := *stored
+	return &response, nil
+}
+
+func (i *FakeIdempotencyUsecase) Complete(ctx context.Context, employeeID int, key string, response entity.IdempotentResponse) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.responses[fakeIdempotencyKey{employeeID, key}] = &response
 	return nil
 }
 
-func setupServer(usecase *FakeEmployeeUsecase) *httptest.Server {
+func (i *FakeIdempotencyUsecase) Release(ctx context.Context, employeeID int, key string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.responses, fakeIdempotencyKey{employeeID, key})
+	return nil
+}
+
+func (i *FakeIdempotencyUsecase) CleanupExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (i *FakeIdempotencyUsecase) RunCleanup(ctx context.Context, interval time.Duration) {}
+
+func setupServer(fake *FakeEmployeeUsecase) *httptest.Server {
 	router := mux.NewRouter()
-	h := handler.NewHandler(usecase, zap.NewNop())
+	h := handler.NewHandler(fake, fake.auth, fake.merch, fake, fake.idempotency, fake, handler.Config{LegacyBuyGet: true}, zap.NewNop())
 	h.RegisterRoutes(router)
 	return httptest.NewServer(router)
 }
 
-func TestAuthNewUser(t *testing.T) {
+func TestRegisterNewUser(t *testing.T) {
 	fake := NewFakeEmployeeUsecase()
 	server := setupServer(fake)
 	defer server.Close()
@@ -162,14 +731,14 @@ func TestAuthNewUser(t *testing.T) {
 		"username": "alice",
 		"password": "password",
 	})
-	res, err := http.Post(server.URL+"/api/auth", "application/json", bytes.NewBuffer(reqBody))
+	res, err := http.Post(server.URL+"/api/register", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
 		t.Fatalf("Не удалось выполнить запрос: %v", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("Ожидался статус 201, получен %d", res.StatusCode)
 	}
 
 	var resp struct {
@@ -183,49 +752,44 @@ func TestAuthNewUser(t *testing.T) {
 	}
 }
 
-func TestAuthExistingIncorrect(t *testing.T) {
+func TestRegisterExistingUser(t *testing.T) {
 	fake := NewFakeEmployeeUsecase()
-	_, err := fake.Authenticate("bob", "secret")
-	if err != nil {
-		t.Fatalf("Ошибка при предварительной аутентификации: %v", err)
+	if _, err := fake.Register(context.Background(), "alice", "password"); err != nil {
+		t.Fatalf("Ошибка при предварительной регистрации: %v", err)
 	}
 	server := setupServer(fake)
 	defer server.Close()
 
 	reqBody, _ := json.Marshal(map[string]string{
-		"username": "bob",
-		"password": "wrong",
+		"username": "alice",
+		"password": "password",
 	})
-	res, err := http.Post(server.URL+"/api/auth", "application/json", bytes.NewBuffer(reqBody))
+	res, err := http.Post(server.URL+"/api/register", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
 		t.Fatalf("Не удалось выполнить запрос: %v", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusUnauthorized {
-		t.Fatalf("Ожидался статус 401, получен %d", res.StatusCode)
+	if res.StatusCode != http.StatusConflict {
+		t.Fatalf("Ожидался статус 409, получен %d", res.StatusCode)
 	}
 }
 
-func TestGetInfoAuthorized(t *testing.T) {
+func TestAuthExistingUser(t *testing.T) {
 	fake := NewFakeEmployeeUsecase()
-	token, err := fake.Authenticate("charlie", "password")
-	if err != nil {
-		t.Fatalf("Ошибка аутентификации: %v", err)
+	if _, err := fake.Register(context.Background(), "alice", "password"); err != nil {
+		t.Fatalf("Ошибка при предварительной регистрации: %v", err)
 	}
 	server := setupServer(fake)
 	defer server.Close()
 
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", server.URL+"/api/info", nil)
-	if err != nil {
-		t.Fatalf("Ошибка создания запроса: %v", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	res, err := client.Do(req)
+	reqBody, _ := json.Marshal(map[string]string{
+		"username": "alice",
+		"password": "password",
+	})
+	res, err := http.Post(server.URL+"/api/auth", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		t.Fatalf("Ошибка выполнения запроса: %v", err)
+		t.Fatalf("Не удалось выполнить запрос: %v", err)
 	}
 	defer res.Body.Close()
 
@@ -233,54 +797,138 @@ func TestGetInfoAuthorized(t *testing.T) {
 		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
 	}
 
-	var info entity.InfoResponse
-	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
 		t.Fatalf("Ошибка декодирования ответа: %v", err)
 	}
-	if info.Coins != 1000 {
-		t.Fatalf("Ожидалось 1000 монет, получено %d", info.Coins)
+	if resp.Token == "" {
+		t.Fatalf("Ожидался непустой токен, получен пустой")
 	}
 }
 
-func TestSendCoin(t *testing.T) {
+func TestAuthUnknownUser(t *testing.T) {
 	fake := NewFakeEmployeeUsecase()
-	tokenDave, err := fake.Authenticate("dave", "pass")
-	if err != nil {
-		t.Fatalf("Ошибка аутентификации dave: %v", err)
-	}
-	_, err = fake.Authenticate("eva", "pass")
-	if err != nil {
-		t.Fatalf("Ошибка аутентификации eva: %v", err)
-	}
 	server := setupServer(fake)
 	defer server.Close()
 
-	client := &http.Client{}
-	reqBody, _ := json.Marshal(map[string]interface{}{
-		"toUser": "eva",
-		"amount": 200,
+	reqBody, _ := json.Marshal(map[string]string{
+		"username": "nobody",
+		"password": "password",
 	})
-	req, err := http.NewRequest("POST", server.URL+"/api/sendCoin", bytes.NewBuffer(reqBody))
-	if err != nil {
-		t.Fatalf("Ошибка создания запроса: %v", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+tokenDave)
-
-	res, err := client.Do(req)
+	res, err := http.Post(server.URL+"/api/auth", "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		t.Fatalf("Ошибка выполнения запроса: %v", err)
+		t.Fatalf("Не удалось выполнить запрос: %v", err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Ожидался статус 401, получен %d", res.StatusCode)
+	}
+	if _, err := fake.GetEmployeeIDByUsername(context.Background(), "nobody"); err == nil {
+		t.Fatalf("Неизвестный пользователь не должен создаваться при входе")
+	}
+}
+
+func TestAuthExistingIncorrect(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	_, err := fake.Register(context.Background(), "bob", "secret")
+	if err != nil {
+		t.Fatalf("Ошибка при предварительной регистрации: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	reqBody, _ := json.Marshal(map[string]string{
+		"username": "bob",
+		"password": "wrong",
+	})
+	res, err := http.Post(server.URL+"/api/auth", "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Не удалось выполнить запрос: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Ожидался статус 401, получен %d", res.StatusCode)
+	}
+}
+
+func TestGetInfoAuthorized(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "charlie", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", server.URL+"/api/info", nil)
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+
+	var info entity.InfoResponse
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if info.Coins != 1000 {
+		t.Fatalf("Ожидалось 1000 монет, получено %d", info.Coins)
+	}
+}
+
+func TestSendCoin(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokenDave, err := fake.Register(context.Background(), "dave", "pass")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации dave: %v", err)
+	}
+	_, err = fake.Register(context.Background(), "eva", "pass")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации eva: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	client := &http.Client{}
+	reqBody, _ := json.Marshal(map[string]interface{}{
+		"toUser": "eva",
+		"amount": 200,
+	})
+	req, err := http.NewRequest("POST", server.URL+"/api/sendCoin", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tokenDave.AccessToken)
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
 	}
 
-	daveID, _ := fake.GetEmployeeIDByUsername("dave")
-	evaID, _ := fake.GetEmployeeIDByUsername("eva")
-	daveInfo, _ := fake.GetEmployeeInfo(daveID)
-	evaInfo, _ := fake.GetEmployeeInfo(evaID)
+	daveID, _ := fake.GetEmployeeIDByUsername(context.Background(), "dave")
+	evaID, _ := fake.GetEmployeeIDByUsername(context.Background(), "eva")
+	daveInfo, _ := fake.GetEmployeeInfo(context.Background(), daveID)
+	evaInfo, _ := fake.GetEmployeeInfo(context.Background(), evaID)
 
 	if daveInfo.Coins != 800 {
 		t.Fatalf("Ожидалось 800 монет у dave, получено %d", daveInfo.Coins)
@@ -292,15 +940,15 @@ func TestSendCoin(t *testing.T) {
 
 func TestSendCoinInsufficientFunds(t *testing.T) {
 	fake := NewFakeEmployeeUsecase()
-	tokenFrank, err := fake.Authenticate("frank", "pass")
+	tokenFrank, err := fake.Register(context.Background(), "frank", "pass")
 	if err != nil {
-		t.Fatalf("Ошибка аутентификации frank: %v", err)
+		t.Fatalf("Ошибка регистрации frank: %v", err)
 	}
-	_, err = fake.Authenticate("gina", "pass")
+	_, err = fake.Register(context.Background(), "gina", "pass")
 	if err != nil {
-		t.Fatalf("Ошибка аутентификации gina: %v", err)
+		t.Fatalf("Ошибка регистрации gina: %v", err)
 	}
-	frankID, _ := fake.GetEmployeeIDByUsername("frank")
+	frankID, _ := fake.GetEmployeeIDByUsername(context.Background(), "frank")
 	fake.mu.Lock()
 	fake.employeesByID[frankID].Coins = 100
 	fake.mu.Unlock()
@@ -318,7 +966,7 @@ func TestSendCoinInsufficientFunds(t *testing.T) {
 		t.Fatalf("Ошибка создания запроса: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+tokenFrank)
+	req.Header.Set("Authorization", "Bearer "+tokenFrank.AccessToken)
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -326,26 +974,35 @@ func TestSendCoinInsufficientFunds(t *testing.T) {
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusInternalServerError {
-		t.Fatalf("Ожидался статус 500, получен %d", res.StatusCode)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Ожидался статус 400, получен %d", res.StatusCode)
+	}
+	var resp handler.ErrorResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if resp.Errors != usecase.ErrInsufficientCoins.Error() {
+		t.Fatalf("Ожидалась ошибка %q, получена %q", usecase.ErrInsufficientCoins.Error(), resp.Errors)
 	}
 }
 
 func TestBuyMerch(t *testing.T) {
 	fake := NewFakeEmployeeUsecase()
-	tokenHarry, err := fake.Authenticate("harry", "pass")
+	tokenHarry, err := fake.Register(context.Background(), "harry", "pass")
 	if err != nil {
-		t.Fatalf("Ошибка аутентификации harry: %v", err)
+		t.Fatalf("Ошибка регистрации harry: %v", err)
 	}
 	server := setupServer(fake)
 	defer server.Close()
 
 	client := &http.Client{}
-	req, err := http.NewRequest("GET", server.URL+"/api/buy/item1", nil)
+	reqBody, _ := json.Marshal(map[string]interface{}{"item": "item1", "quantity": 2})
+	req, err := http.NewRequest("POST", server.URL+"/api/buy", bytes.NewBuffer(reqBody))
 	if err != nil {
 		t.Fatalf("Ошибка создания запроса: %v", err)
 	}
-	req.Header.Set("Authorization", "Bearer "+tokenHarry)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+tokenHarry.AccessToken)
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -357,13 +1014,89 @@ func TestBuyMerch(t *testing.T) {
 		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
 	}
 
-	harryID, _ := fake.GetEmployeeIDByUsername("harry")
-	info, _ := fake.GetEmployeeInfo(harryID)
-	if info.Coins != 950 {
-		t.Fatalf("Ожидалось 950 монет у harry, получено %d", info.Coins)
+	harryID, _ := fake.GetEmployeeIDByUsername(context.Background(), "harry")
+	info, _ := fake.GetEmployeeInfo(context.Background(), harryID)
+	if info.Coins != 900 {
+		t.Fatalf("Ожидалось 900 монет у harry, получено %d", info.Coins)
+	}
+	if len(info.Inventory) == 0 || info.Inventory[0].Type != "item1" || info.Inventory[0].Quantity != 2 {
+		t.Fatalf("Ожидался товар item1 в инвентаре с количеством 2")
+	}
+
+	req, _ = http.NewRequest("GET", server.URL+"/api/info", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenHarry.AccessToken)
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	var infoResp entity.InfoResponse
+	if err := json.NewDecoder(res.Body).Decode(&infoResp); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	purchase := infoResp.Purchases
+	if len(purchase) != 1 || purchase[0].Item != "item1" || purchase[0].Price != 50 || purchase[0].Quantity != 2 || purchase[0].CreatedAt.IsZero() {
+		t.Fatalf("Ожидалась покупка двух item1 по 50 монет в истории, получено %+v", infoResp.Purchases)
+	}
+}
+
+func TestBuyMerchValidation(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "irene", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации irene: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+	}{
+		{"missing item", map[string]interface{}{"quantity": 1}},
+		{"missing quantity", map[string]interface{}{"item": "cup"}},
+		{"negative quantity", map[string]interface{}{"item": "cup", "quantity": -1}},
+		{"too many", map[string]interface{}{"item": "cup", "quantity": usecase.MaxPurchaseQuantity + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doAdminRequest(t, "POST", server.URL+"/api/buy", token.AccessToken, tt.body)
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Ожидался статус 400, получен %d", res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestLegacyBuyRoute(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "jack", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации jack: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	res := doAdminRequest(t, "GET", server.URL+"/api/buy/cup", token.AccessToken, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
 	}
-	if len(info.Inventory) == 0 || info.Inventory[0].Type != "item1" || info.Inventory[0].Quantity != 1 {
-		t.Fatalf("Ожидался товар item1 в инвентаре с количеством 1")
+	if res.Header.Get("Deprecation") == "" {
+		t.Fatalf("Ожидался заголовок Deprecation у устаревшего маршрута")
+	}
+	jackID, _ := fake.GetEmployeeIDByUsername(context.Background(), "jack")
+	if info, _ := fake.GetEmployeeInfo(context.Background(), jackID); info.Coins != 950 {
+		t.Fatalf("Ожидалось 950 монет у jack, получено %d", info.Coins)
+	}
+
+	// Без флага устаревший маршрут не регистрируется
+	router := mux.NewRouter()
+	handler.NewHandler(fake, fake.auth, fake.merch, fake, fake.idempotency, fake, handler.Config{}, zap.NewNop()).RegisterRoutes(router)
+	strict := httptest.NewServer(router)
+	defer strict.Close()
+	res = doAdminRequest(t, "GET", strict.URL+"/api/buy/cup", token.AccessToken, nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("Ожидался статус 404 без устаревшего маршрута, получен %d", res.StatusCode)
 	}
 }
 
@@ -388,3 +1121,942 @@ func TestAccessWithoutToken(t *testing.T) {
 		t.Fatalf("Ожидался статус 401, получен %d", res.StatusCode)
 	}
 }
+
+func TestJWKS(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	server := setupServer(fake)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/.well-known/jwks.json")
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+
+	var jwks jwt.JWKS
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if len(jwks.Keys) != 0 {
+		t.Fatalf("HMAC-ключи не должны публиковаться, получено %d ключей", len(jwks.Keys))
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokens, err := fake.Register(context.Background(), "ivan", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации ivan: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	refresh := func(refreshToken string) *http.Response {
+		reqBody, _ := json.Marshal(map[string]string{"refreshToken": refreshToken})
+		res, err := http.Post(server.URL+"/api/auth/refresh", "application/json", bytes.NewBuffer(reqBody))
+		if err != nil {
+			t.Fatalf("Ошибка выполнения запроса: %v", err)
+		}
+		return res
+	}
+
+	res := refresh(tokens.RefreshToken)
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+	var rotated entity.TokenPair
+	if err := json.NewDecoder(res.Body).Decode(&rotated); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if rotated.AccessToken == "" || rotated.RefreshToken == "" || rotated.RefreshToken == tokens.RefreshToken {
+		t.Fatalf("Ожидалась новая пара токенов")
+	}
+
+	reused := refresh(tokens.RefreshToken)
+	defer reused.Body.Close()
+	if reused.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Повторное использование refresh-токена: ожидался статус 401, получен %d", reused.StatusCode)
+	}
+}
+
+func TestLogoutRevokesAccessToken(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokens, err := fake.Register(context.Background(), "julia", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации julia: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	client := &http.Client{}
+	reqBody, _ := json.Marshal(map[string]string{"refreshToken": tokens.RefreshToken})
+	req, err := http.NewRequest("POST", server.URL+"/api/auth/logout", bytes.NewBuffer(reqBody))
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("Ожидался статус 204, получен %d", res.StatusCode)
+	}
+
+	req, err = http.NewRequest("GET", server.URL+"/api/info", nil)
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Отозванный токен: ожидался статус 401, получен %d", res.StatusCode)
+	}
+
+	if _, err := fake.auth.Refresh(context.Background(), tokens.RefreshToken); err == nil {
+		t.Fatalf("Refresh-токен должен быть отозван после выхода")
+	}
+}
+
+func doAdminRequest(t *testing.T, method, url, token string, body interface{}) *http.Response {
+	var reqBody bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&reqBody).Encode(body)
+	}
+	req, err := http.NewRequest(method, url, &reqBody)
+	if err != nil {
+		t.Fatalf("Ошибка создания запроса: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	res.Body.Close()
+	return res
+}
+
+func TestAdminRoutesRequireRole(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	if _, err := fake.Register(context.Background(), "root", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации root: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "olga", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации olga: %v", err)
+	}
+	tokenUser, err := fake.Register(context.Background(), "paul", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации paul: %v", err)
+	}
+	fake.SetEmployeeRole(context.Background(), "root", entity.RoleAdmin)
+	fake.SetEmployeeRole(context.Background(), "olga", entity.RoleAuditor)
+	tokenAdmin, _ := fake.Authenticate(context.Background(), "root", "password")
+	tokenAuditor, _ := fake.Authenticate(context.Background(), "olga", "password")
+
+	server := setupServer(fake)
+	defer server.Close()
+
+	infoURL := server.URL + "/api/admin/employees/paul"
+	roleURL := server.URL + "/api/admin/employees/paul/role"
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"employee reads", "GET", infoURL, tokenUser.AccessToken, nil, http.StatusForbidden},
+		{"auditor reads", "GET", infoURL, tokenAuditor.AccessToken, nil, http.StatusOK},
+		{"admin reads", "GET", infoURL, tokenAdmin.AccessToken, nil, http.StatusOK},
+		{"admin reads unknown", "GET", server.URL + "/api/admin/employees/nobody", tokenAdmin.AccessToken, nil, http.StatusNotFound},
+		{"auditor writes", "PUT", roleURL, tokenAuditor.AccessToken, map[string]string{"role": "admin"}, http.StatusForbidden},
+		{"admin sets invalid role", "PUT", roleURL, tokenAdmin.AccessToken, map[string]string{"role": "root"}, http.StatusBadRequest},
+		{"admin writes", "PUT", roleURL, tokenAdmin.AccessToken, map[string]string{"role": "auditor"}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doAdminRequest(t, tt.method, tt.url, tt.token, tt.body)
+			if res.StatusCode != tt.status {
+				t.Fatalf("Ожидался статус %d, получен %d", tt.status, res.StatusCode)
+			}
+		})
+	}
+
+	// После смены роли старый токен paul больше не принимается
+	res := doAdminRequest(t, "GET", server.URL+"/api/info", tokenUser.AccessToken, nil)
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Ожидался статус 401, получен %d", res.StatusCode)
+	}
+}
+
+func TestAdminMerchCatalog(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	if _, err := fake.Register(context.Background(), "root", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации root: %v", err)
+	}
+	tokenUser, err := fake.Register(context.Background(), "quinn", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации quinn: %v", err)
+	}
+	fake.SetEmployeeRole(context.Background(), "root", entity.RoleAdmin)
+	tokenAdmin, _ := fake.Authenticate(context.Background(), "root", "password")
+
+	server := setupServer(fake)
+	defer server.Close()
+
+	merchURL := server.URL + "/api/admin/merch"
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"employee creates", "POST", merchURL, tokenUser.AccessToken, map[string]interface{}{"name": "sticker", "price": 5}, http.StatusForbidden},
+		{"admin creates", "POST", merchURL, tokenAdmin.AccessToken, map[string]interface{}{"name": "sticker", "price": 5}, http.StatusCreated},
+		{"admin creates duplicate", "POST", merchURL, tokenAdmin.AccessToken, map[string]interface{}{"name": "sticker", "price": 7}, http.StatusConflict},
+		{"admin creates free item", "POST", merchURL, tokenAdmin.AccessToken, map[string]interface{}{"name": "gift", "price": 0}, http.StatusBadRequest},
+		{"admin reprices", "PUT", merchURL + "/1", tokenAdmin.AccessToken, map[string]interface{}{"price": 6}, http.StatusOK},
+		{"admin reprices negative", "PUT", merchURL + "/1", tokenAdmin.AccessToken, map[string]interface{}{"price": -1}, http.StatusBadRequest},
+		{"admin deletes", "DELETE", merchURL + "/1", tokenAdmin.AccessToken, nil, http.StatusNoContent},
+		{"admin deletes twice", "DELETE", merchURL + "/1", tokenAdmin.AccessToken, nil, http.StatusNotFound},
+		{"admin reads deleted", "GET", merchURL + "/1", tokenAdmin.AccessToken, nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doAdminRequest(t, tt.method, tt.url, tt.token, tt.body)
+			if res.StatusCode != tt.status {
+				t.Fatalf("Ожидался статус %d, получен %d", tt.status, res.StatusCode)
+			}
+		})
+	}
+
+	active, _ := fake.merch.ListMerch(context.Background(), false)
+	all, _ := fake.merch.ListMerch(context.Background(), true)
+	if len(active) != 0 || len(all) != 1 || all[0].Price != 6 {
+		t.Fatalf("Ожидался один снятый с продажи товар с ценой 6, получено %+v", all)
+	}
+}
+
+func TestCatalogETag(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	fake.merch.CreateMerch(context.Background(), entity.Merch{Name: "cup", Price: 20, Category: "accessories"})
+	fake.merch.CreateMerch(context.Background(), entity.Merch{Name: "hoody", Price: 300, Category: "clothing"})
+	server := setupServer(fake)
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/merch?maxPrice=100")
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+	var catalog []entity.Merch
+	if err := json.NewDecoder(res.Body).Decode(&catalog); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if len(catalog) != 1 || catalog[0].Name != "cup" {
+		t.Fatalf("Ожидался только товар cup, получено %+v", catalog)
+	}
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("Ожидался заголовок ETag")
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/merch?maxPrice=100", nil)
+	req.Header.Set("If-None-Match", etag)
+	cached, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	cached.Body.Close()
+	if cached.StatusCode != http.StatusNotModified {
+		t.Fatalf("Ожидался статус 304, получен %d", cached.StatusCode)
+	}
+
+	// После изменения цены каталог отдаётся заново
+	fake.merch.UpdateMerch(context.Background(), entity.Merch{ID: 1, Price: 25, Category: "accessories"})
+	changed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	changed.Body.Close()
+	if changed.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", changed.StatusCode)
+	}
+
+	bad, err := http.Get(server.URL + "/api/merch?minPrice=abc")
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Ожидался статус 400, получен %d", bad.StatusCode)
+	}
+}
+
+func TestRestockAndOutOfStock(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	if _, err := fake.Register(context.Background(), "root", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации root: %v", err)
+	}
+	tokenUser, err := fake.Register(context.Background(), "rita", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации rita: %v", err)
+	}
+	fake.SetEmployeeRole(context.Background(), "root", entity.RoleAdmin)
+	tokenAdmin, _ := fake.Authenticate(context.Background(), "root", "password")
+	fake.merch.CreateMerch(context.Background(), entity.Merch{Name: "sticker", Price: 5})
+
+	server := setupServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"buy without stock", "POST", server.URL + "/api/buy", tokenUser.AccessToken, map[string]interface{}{"item": "sticker", "quantity": 1}, http.StatusConflict},
+		{"employee restocks", "POST", server.URL + "/api/admin/merch/1/restock", tokenUser.AccessToken, map[string]interface{}{"quantity": 1}, http.StatusForbidden},
+		{"admin restocks zero", "POST", server.URL + "/api/admin/merch/1/restock", tokenAdmin.AccessToken, map[string]interface{}{"quantity": 0}, http.StatusBadRequest},
+		{"admin restocks", "POST", server.URL + "/api/admin/merch/1/restock", tokenAdmin.AccessToken, map[string]interface{}{"quantity": 1, "comment": "поставка"}, http.StatusOK},
+		{"buy more than in stock", "POST", server.URL + "/api/buy", tokenUser.AccessToken, map[string]interface{}{"item": "sticker", "quantity": 2}, http.StatusConflict},
+		{"buy last item", "POST", server.URL + "/api/buy", tokenUser.AccessToken, map[string]interface{}{"item": "sticker", "quantity": 1}, http.StatusOK},
+		{"buy again", "POST", server.URL + "/api/buy", tokenUser.AccessToken, map[string]interface{}{"item": "sticker", "quantity": 1}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doAdminRequest(t, tt.method, tt.url, tt.token, tt.body)
+			if res.StatusCode != tt.status {
+				t.Fatalf("Ожидался статус %d, получен %d", tt.status, res.StatusCode)
+			}
+		})
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/admin/merch/1/stock", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenAdmin.AccessToken)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	var report entity.StockReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if report.Stock != 0 || report.Restocked != 1 || report.Sold != 1 || len(report.Movements) != 1 {
+		t.Fatalf("Неожиданный отчёт по складу: %+v", report)
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "sam", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации sam: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		body    interface{}
+		buyErr  error
+		status  int
+		message string
+	}{
+		{"self transfer", "POST", server.URL + "/api/sendCoin", map[string]interface{}{"toUser": "sam", "amount": 10}, nil, http.StatusBadRequest, usecase.ErrSelfTransfer.Error()},
+		{"unknown recipient", "POST", server.URL + "/api/sendCoin", map[string]interface{}{"toUser": "nobody", "amount": 10}, nil, http.StatusBadRequest, usecase.ErrRecipientNotFound.Error()},
+		{"unknown item", "GET", server.URL + "/api/buy/unknown", nil, usecase.ErrItemNotFound, http.StatusNotFound, usecase.ErrItemNotFound.Error()},
+		{"database failure", "GET", server.URL + "/api/buy/cup", nil, errors.New(`ERROR: relation "merch" does not exist (SQLSTATE 42P01)`), http.StatusInternalServerError, "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.buyErr = tt.buyErr
+			var reqBody bytes.Buffer
+			if tt.body != nil {
+				json.NewEncoder(&reqBody).Encode(tt.body)
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, &reqBody)
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Ошибка выполнения запроса: %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != tt.status {
+				t.Fatalf("Ожидался статус %d, получен %d", tt.status, res.StatusCode)
+			}
+			var resp handler.ErrorResponse
+			if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+				t.Fatalf("Ошибка декодирования ответа: %v", err)
+			}
+			if resp.Errors != tt.message {
+				t.Fatalf("Ожидалась ошибка %q, получена %q", tt.message, resp.Errors)
+			}
+		})
+	}
+}
+
+func TestSendCoinValidation(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "sam", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации sam: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "alex", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации alex: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"negative amount", `{"toUser": "alex", "amount": -10}`, "amount must be a positive integer"},
+		{"zero amount", `{"toUser": "alex", "amount": 0}`, "amount must be a positive integer"},
+		{"amount too large", `{"toUser": "alex", "amount": 100001}`, "amount must not exceed 100000"},
+		{"fractional amount", `{"toUser": "alex", "amount": 1.5}`, "Invalid request"},
+		{"empty recipient", `{"toUser": "", "amount": 10}`, "toUser is required"},
+		{"recipient too long", `{"toUser": "` + strings.Repeat("a", 101) + `", "amount": 10}`, "toUser must be at most 100 characters long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", server.URL+"/api/sendCoin", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Ошибка выполнения запроса: %v", err)
+			}
+			defer res.Body.Close()
+
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Ожидался статус %d, получен %d", http.StatusBadRequest, res.StatusCode)
+			}
+			var resp handler.ErrorResponse
+			if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+				t.Fatalf("Ошибка декодирования ответа: %v", err)
+			}
+			if resp.Errors != tt.message {
+				t.Fatalf("Ожидалась ошибка %q, получена %q", tt.message, resp.Errors)
+			}
+		})
+	}
+
+	samID, _ := fake.GetEmployeeIDByUsername(context.Background(), "sam")
+	info, _ := fake.GetEmployeeInfo(context.Background(), samID)
+	if info.Coins != 1000 {
+		t.Fatalf("Баланс не должен был измениться, получено %d", info.Coins)
+	}
+}
+
+func TestLedgerCheck(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokenSam, err := fake.Register(context.Background(), "sam", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации sam: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "alex", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации alex: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "olga", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации olga: %v", err)
+	}
+	fake.SetEmployeeRole(context.Background(), "olga", entity.RoleAuditor)
+	tokenAuditor, _ := fake.Authenticate(context.Background(), "olga", "password")
+	fake.merch.CreateMerch(context.Background(), entity.Merch{Name: "cup", Price: 20})
+	fake.merch.RestockMerch(context.Background(), 1, 1, 0, "")
+
+	server := setupServer(fake)
+	defer server.Close()
+
+	res := doAdminRequest(t, "POST", server.URL+"/api/sendCoin", tokenSam.AccessToken, map[string]interface{}{"toUser": "alex", "amount": 100})
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+	res = doAdminRequest(t, "GET", server.URL+"/api/buy/cup", tokenSam.AccessToken, nil)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+
+	res = doAdminRequest(t, "GET", server.URL+"/api/admin/ledger/check", tokenSam.AccessToken, nil)
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("Ожидался статус 403, получен %d", res.StatusCode)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/admin/ledger/check", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenAuditor.AccessToken)
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+	var report entity.LedgerReport
+	if err := json.NewDecoder(res.Body).Decode(&report); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if !report.Conserved || report.Issued != 3000 || report.Store != 50 || report.Employees != 2950 {
+		t.Fatalf("Неожиданный отчёт по журналу: %+v", report)
+	}
+}
+
+func TestCoinHistoryPagination(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokenSam, err := fake.Register(context.Background(), "sam", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации sam: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "alex", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации alex: %v", err)
+	}
+	samID, _ := fake.GetEmployeeIDByUsername(context.Background(), "sam")
+	alexID, _ := fake.GetEmployeeIDByUsername(context.Background(), "alex")
+	for amount := 1; amount <= 5; amount++ {
+		fake.TransferCoins(context.Background(), samID, alexID, amount, entity.Memo{})
+	}
+	fake.TransferCoins(context.Background(), alexID, samID, 10, entity.Memo{})
+
+	server := setupServer(fake)
+	defer server.Close()
+
+	getPage := func(query string) entity.HistoryPage {
+		req, _ := http.NewRequest("GET", server.URL+"/api/history?"+query, nil)
+		req.Header.Set("Authorization", "Bearer "+tokenSam.AccessToken)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения запроса: %v", err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+		}
+		var page entity.HistoryPage
+		if err := json.NewDecoder(res.Body).Decode(&page); err != nil {
+			t.Fatalf("Ошибка декодирования ответа: %v", err)
+		}
+		return page
+	}
+
+	var amounts []int
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("Слишком много страниц")
+		}
+		page := getPage("direction=sent&limit=2&cursor=" + cursor)
+		for _, entry := range page.Items {
+			if entry.Direction != entity.DirectionSent || entry.FromUser != "sam" || entry.ToUser != "alex" || entry.CreatedAt.IsZero() {
+				t.Fatalf("Неожиданная запись истории: %+v", entry)
+			}
+			amounts = append(amounts, entry.Amount)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if fmt.Sprint(amounts) != "[5 4 3 2 1]" {
+		t.Fatalf("Ожидались переводы от новых к старым, получено %v", amounts)
+	}
+
+	received := getPage("direction=received")
+	if len(received.Items) != 1 || received.Items[0].Amount != 10 || received.NextCursor != "" {
+		t.Fatalf("Ожидался один полученный перевод, получено %+v", received)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/info", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenSam.AccessToken)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	var info entity.InfoResponse
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if len(info.CoinHistory.Received) != 1 || info.CoinHistory.Received[0].FromUser != "alex" {
+		t.Fatalf("Ожидался перевод от alex, получено %+v", info.CoinHistory.Received)
+	}
+	if len(info.CoinHistory.Sent) != 5 || info.CoinHistory.Sent[0].ToUser != "alex" {
+		t.Fatalf("Ожидались переводы для alex, получено %+v", info.CoinHistory.Sent)
+	}
+
+	future := url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))
+	if page := getPage("from=" + future); len(page.Items) != 0 {
+		t.Fatalf("Ожидалась пустая история, получено %+v", page.Items)
+	}
+
+	for _, query := range []string{"from=yesterday", "limit=abc", "limit=1000", "cursor=bogus"} {
+		res := doAdminRequest(t, "GET", server.URL+"/api/history?"+query, tokenSam.AccessToken, nil)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Запрос %q: ожидался статус 400, получен %d", query, res.StatusCode)
+		}
+	}
+}
+
+func TestKudosWall(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokenSam, err := fake.Register(context.Background(), "sam", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации sam: %v", err)
+	}
+	tokenAlex, err := fake.Register(context.Background(), "alex", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации alex: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	transfers := []struct {
+		token string
+		body  map[string]interface{}
+	}{
+		{tokenSam.AccessToken, map[string]interface{}{"toUser": "alex", "amount": 10, "message": "за ревью", "public": true}},
+		{tokenSam.AccessToken, map[string]interface{}{"toUser": "alex", "amount": 20, "message": "личное"}},
+		{tokenAlex.AccessToken, map[string]interface{}{"toUser": "sam", "amount": 5, "message": "за помощь с релизом", "public": true}},
+	}
+	for _, transfer := range transfers {
+		res := doAdminRequest(t, "POST", server.URL+"/api/sendCoin", transfer.token, transfer.body)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+		}
+	}
+
+	// Опубликованные переводы видны только сотрудникам
+	anonymous, err := http.Get(server.URL + "/api/kudos")
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	anonymous.Body.Close()
+	if anonymous.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Ожидался статус 401 без токена, получен %d", anonymous.StatusCode)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/kudos", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenSam.AccessToken)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200, получен %d", res.StatusCode)
+	}
+	var kudos []entity.Kudos
+	if err := json.NewDecoder(res.Body).Decode(&kudos); err != nil {
+		t.Fatalf("Ошибка декодирования ответа: %v", err)
+	}
+	if len(kudos) != 2 {
+		t.Fatalf("Ожидалось 2 опубликованных перевода, получено %+v", kudos)
+	}
+	if kudos[0].FromUser != "alex" || kudos[0].Message != "за помощь с релизом" || kudos[1].FromUser != "sam" || kudos[1].Message != "за ревью" {
+		t.Fatalf("Неожиданная стена благодарностей: %+v", kudos)
+	}
+
+	// Неопубликованное сообщение видно только участникам перевода
+	samID, _ := fake.GetEmployeeIDByUsername(context.Background(), "sam")
+	page, _ := fake.GetCoinHistory(context.Background(), samID, entity.HistoryFilter{Direction: entity.DirectionSent}, "", 0)
+	if len(page.Items) != 2 || page.Items[0].Message != "личное" || page.Items[0].Public {
+		t.Fatalf("Ожидалось личное сообщение в истории, получено %+v", page.Items)
+	}
+}
+
+func TestSendCoinIdempotencyKey(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	tokenNick, err := fake.Register(context.Background(), "nick", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации nick: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "olga", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации olga: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	sendCoin := func(key string, amount int) *http.Response {
+		reqBody, _ := json.Marshal(map[string]interface{}{"toUser": "olga", "amount": amount})
+		req, _ := http.NewRequest("POST", server.URL+"/api/sendCoin", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokenNick.AccessToken)
+		req.Header.Set("Idempotency-Key", key)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения запроса: %v", err)
+		}
+		res.Body.Close()
+		return res
+	}
+
+	res := sendCoin("transfer-1", 100)
+	if res.StatusCode != http.StatusOK || res.Header.Get("Idempotent-Replayed") != "" {
+		t.Fatalf("Ожидался статус 200 без повтора, получен %d", res.StatusCode)
+	}
+	// Повтор с тем же ключом возвращает сохранённый ответ и не списывает монеты второй раз
+	res = sendCoin("transfer-1", 100)
+	if res.StatusCode != http.StatusOK || res.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Ожидался повтор сохранённого ответа, получен статус %d", res.StatusCode)
+	}
+	nickID, _ := fake.GetEmployeeIDByUsername(context.Background(), "nick")
+	info, _ := fake.GetEmployeeInfo(context.Background(), nickID)
+	if info.Coins != 900 {
+		t.Fatalf("Ожидалось 900 монет у nick, получено %d", info.Coins)
+	}
+
+	if res := sendCoin("transfer-1", 50); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Ожидался статус 400 для другого запроса с тем же ключом, получен %d", res.StatusCode)
+	}
+	if res := sendCoin("transfer-2", 50); res.StatusCode != http.StatusOK {
+		t.Fatalf("Ожидался статус 200 для нового ключа, получен %d", res.StatusCode)
+	}
+	info, _ = fake.GetEmployeeInfo(context.Background(), nickID)
+	if info.Coins != 850 {
+		t.Fatalf("Ожидалось 850 монет у nick, получено %d", info.Coins)
+	}
+}
+
+func TestIdempotencyKeyRejectsOversizedBody(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "nick", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации nick: %v", err)
+	}
+	server := setupServer(fake)
+	defer server.Close()
+
+	// Тело длиннее 1 МиБ не обрезается для отпечатка, а отклоняется целиком
+	reqBody := `{"toUser": "olga", "amount": 1, "message": "` + strings.Repeat("a", 1<<20) + `"}`
+	req, _ := http.NewRequest("POST", server.URL+"/api/sendCoin", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Idempotency-Key", "oversized")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Ожидался статус 413, получен %d", res.StatusCode)
+	}
+}
+
+func TestCartCheckout(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "kate", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации kate: %v", err)
+	}
+	fake.merch.CreateMerch(context.Background(), entity.Merch{Name: "hoody", Price: 300})
+	fake.merch.RestockMerch(context.Background(), 1, 1, 0, "")
+	server := setupServer(fake)
+	defer server.Close()
+
+	cartRequest := func(method, path string, body interface{}) (int, entity.Cart) {
+		var reqBody bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&reqBody).Encode(body)
+		}
+		req, _ := http.NewRequest(method, server.URL+path, &reqBody)
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения запроса: %v", err)
+		}
+		defer res.Body.Close()
+		var cart entity.Cart
+		if res.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(res.Body).Decode(&cart); err != nil {
+				t.Fatalf("Ошибка декодирования ответа: %v", err)
+			}
+		}
+		return res.StatusCode, cart
+	}
+
+	if status, _ := cartRequest("POST", "/api/cart/checkout", nil); status != http.StatusBadRequest {
+		t.Fatalf("Ожидался статус 400 для пустой корзины, получен %d", status)
+	}
+	for _, line := range []map[string]interface{}{
+		{"item": "socks", "quantity": 2},
+		{"item": "hoody", "quantity": 2},
+		{"item": "cup", "quantity": 1},
+	} {
+		if status, _ := cartRequest("POST", "/api/cart/items", line); status != http.StatusOK {
+			t.Fatalf("Ожидался статус 200 при добавлении в корзину, получен %d", status)
+		}
+	}
+	status, cart := cartRequest("GET", "/api/cart", nil)
+	if status != http.StatusOK || len(cart.Items) != 3 || cart.Total != 250 {
+		t.Fatalf("Ожидалась корзина из трёх позиций на 250 монет, получено %d %+v", status, cart)
+	}
+
+	// На складе одна толстовка: заказ не оформляется целиком, монеты не списываются
+	if status, _ := cartRequest("POST", "/api/cart/checkout", nil); status != http.StatusConflict {
+		t.Fatalf("Ожидался статус 409, получен %d", status)
+	}
+	kateID, _ := fake.GetEmployeeIDByUsername(context.Background(), "kate")
+	if info, _ := fake.GetEmployeeInfo(context.Background(), kateID); info.Coins != 1000 || len(info.Inventory) != 0 {
+		t.Fatalf("Ожидалось, что неудачный заказ ничего не изменит, получено %+v", info)
+	}
+
+	if status, _ := cartRequest("DELETE", "/api/cart/items/hoody", nil); status != http.StatusOK {
+		t.Fatalf("Ожидался статус 200 при удалении из корзины, получен %d", status)
+	}
+	if status, _ := cartRequest("DELETE", "/api/cart/items/hoody", nil); status != http.StatusNotFound {
+		t.Fatalf("Ожидался статус 404 для позиции не из корзины, получен %d", status)
+	}
+	status, order := cartRequest("POST", "/api/cart/checkout", nil)
+	if status != http.StatusOK || order.Total != 150 || len(order.Items) != 2 {
+		t.Fatalf("Ожидался заказ из двух позиций на 150 монет, получено %d %+v", status, order)
+	}
+	if info, _ := fake.GetEmployeeInfo(context.Background(), kateID); info.Coins != 850 || len(info.Purchases) != 2 {
+		t.Fatalf("Ожидалось 850 монет и две покупки, получено %+v", info)
+	}
+	if _, cart := cartRequest("GET", "/api/cart", nil); len(cart.Items) != 0 {
+		t.Fatalf("Ожидалась пустая корзина после заказа, получено %+v", cart)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	var databaseErr error
+	health := handler.NewHealthHandler(handler.BuildInfo{Version: "1.2.0", Commit: "0123abc"}, []handler.ReadinessCheck{
+		{Name: "database", Check: func(ctx context.Context) error { return databaseErr }},
+		{Name: "migrations", Check: func(ctx context.Context) error { return nil }},
+	}, zap.NewNop())
+	router := mux.NewRouter()
+	health.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path string) (int, map[string]interface{}) {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения запроса: %v", err)
+		}
+		defer res.Body.Close()
+		var body map[string]interface{}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatalf("Ошибка декодирования ответа: %v", err)
+		}
+		return res.StatusCode, body
+	}
+
+	if status, body := get("/healthz"); status != http.StatusOK || body["status"] != "ok" {
+		t.Fatalf("Ожидался статус 200 от /healthz, получено %d %v", status, body)
+	}
+	if status, body := get("/version"); status != http.StatusOK || body["version"] != "1.2.0" || body["commit"] != "0123abc" {
+		t.Fatalf("Ожидались сведения о сборке, получено %d %v", status, body)
+	}
+	if status, body := get("/readyz"); status != http.StatusOK || body["status"] != "ready" {
+		t.Fatalf("Ожидался статус 200 от /readyz, получено %d %v", status, body)
+	}
+
+	// Недоступная база делает сервис неготовым, но текст ошибки не раскрывается
+	databaseErr = errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	status, body := get("/readyz")
+	checks, _ := body["checks"].(map[string]interface{})
+	if status != http.StatusServiceUnavailable || checks["database"] != "failed" || checks["migrations"] != "ok" {
+		t.Fatalf("Ожидался статус 503 с проваленной проверкой database, получено %d %v", status, body)
+	}
+	if status, _ := get("/healthz"); status != http.StatusOK {
+		t.Fatalf("Ожидалось, что /healthz не зависит от базы, получен %d", status)
+	}
+
+	// При остановке /readyz отвечает 503 даже при исправных зависимостях
+	databaseErr = nil
+	health.SetDraining()
+	if status, body := get("/readyz"); status != http.StatusServiceUnavailable || body["status"] != "draining" {
+		t.Fatalf("Ожидался статус 503 при остановке, получено %d %v", status, body)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	fake := NewFakeEmployeeUsecase()
+	token, err := fake.Register(context.Background(), "liam", "password")
+	if err != nil {
+		t.Fatalf("Ошибка регистрации liam: %v", err)
+	}
+	if _, err := fake.Register(context.Background(), "mia", "password"); err != nil {
+		t.Fatalf("Ошибка регистрации mia: %v", err)
+	}
+	registry := prometheus.NewRegistry()
+	httpMetrics := handler.NewMetrics(registry)
+	router := mux.NewRouter()
+	router.Use(httpMetrics.Middleware)
+	h := handler.NewHandler(fake, fake.auth, fake.merch, fake, fake.idempotency, fake, handler.Config{Metrics: httpMetrics}, zap.NewNop())
+	h.RegisterRoutes(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	// Метрики отдаются отдельным сервером, а не публичным API
+	metricsServer := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer metricsServer.Close()
+
+	doAdminRequest(t, "POST", server.URL+"/api/sendCoin", token.AccessToken, map[string]interface{}{"toUser": "mia", "amount": 30})
+	// Повтор по Idempotency-Key не учитывается второй раз
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("POST", server.URL+"/api/buy", strings.NewReader(`{"item": "cup", "quantity": 2}`))
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		req.Header.Set("Idempotency-Key", "metrics-buy")
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Ошибка выполнения запроса: %v", err)
+		}
+		res.Body.Close()
+	}
+	if res := doAdminRequest(t, "POST", server.URL+"/api/buy", token.AccessToken, map[string]interface{}{"item": "pen", "quantity": 100}); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Ожидался статус 400 при нехватке монет, получен %d", res.StatusCode)
+	}
+	if res := doAdminRequest(t, "POST", server.URL+"/api/auth", "", map[string]string{"username": "liam", "password": "wrong-password"}); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Ожидался статус 401 при неверном пароле, получен %d", res.StatusCode)
+	}
+
+	if res, _ := http.Get(server.URL + "/metrics"); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("Ожидался статус 404 для /metrics в публичном API, получен %d", res.StatusCode)
+	}
+	res, err := http.Get(metricsServer.URL)
+	if err != nil {
+		t.Fatalf("Ошибка выполнения запроса: %v", err)
+	}
+	defer res.Body.Close()
+	var body strings.Builder
+	if _, err := io.Copy(&body, res.Body); err != nil {
+		t.Fatalf("Ошибка чтения ответа: %v", err)
+	}
+	for _, line := range []string{
+		"merch_store_coins_transferred_total 30",
+		`merch_store_items_purchased_total{item="cup"} 2`,
+		`merch_store_insufficient_coins_total{operation="purchase"} 1`,
+		`merch_store_auth_failures_total{endpoint="auth"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/api/buy",status="200"} 2`,
+		`http_request_duration_seconds_count{method="POST",route="/api/buy",status="400"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/api/auth",status="401"} 1`,
+	} {
+		if !strings.Contains(body.String(), line+"\n") {
+			t.Errorf("Ожидалась строка %q в /metrics:\n%s", line, body.String())
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package entity
+
+// CartItem — позиция корзины. Price — текущая цена единицы товара,
+// в корзине она не фиксируется и берётся из каталога при каждом запросе
+type CartItem struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+}
+
+// Cart — содержимое корзины или оформленный заказ с итоговой суммой
+type Cart struct {
+	Items []CartItem `json:"items"`
+	Total int        `json:"total"`
+}
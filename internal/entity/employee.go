@@ -1,9 +1,12 @@
 package entity
 
+import "time"
+
 type InfoResponse struct {
 	Coins       int         `json:"coins"`
 	Inventory   []Inventory `json:"inventory"`
 	CoinHistory CoinHistory `json:"coinHistory"`
+	Purchases   []Purchase  `json:"purchases"`
 }
 
 type Inventory struct {
@@ -11,14 +14,96 @@ type Inventory struct {
 	Quantity int    `json:"quantity"`
 }
 
+// Purchase — покупка товара. Price — цена единицы товара на момент покупки
+type Purchase struct {
+	Item      string    `json:"item"`
+	Price     int       `json:"price"`
+	Quantity  int       `json:"quantity"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 type CoinHistory struct {
 	Received []Transaction `json:"received"`
 	Sent     []Transaction `json:"sent"`
 }
 
+// Transaction — перевод в coinHistory. У полученного перевода заполнен
+// FromUser, у отправленного — ToUser
 type Transaction struct {
-	UserID int `json:"user"`
-	Amount int `json:"amount"`
+	FromUser  string    `json:"fromUser,omitempty"`
+	ToUser    string    `json:"toUser,omitempty"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Memo — сообщение к переводу. Public разрешает показывать перевод на стене благодарностей
+type Memo struct {
+	Message string
+	Public  bool
+}
+
+// Kudos — перевод, опубликованный на стене благодарностей
+type Kudos struct {
+	ID        int       `json:"id"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Направления перевода в истории сотрудника
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+// HistoryFilter ограничивает выборку истории переводов. Пустое направление
+// означает оба направления, нулевое время не ограничивает период
+type HistoryFilter struct {
+	Direction string
+	From      time.Time
+	To        time.Time
+}
+
+// HistoryCursor — позиция в истории, отсортированной по убыванию времени и id
+type HistoryCursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// HistoryEntry — перевод в истории сотрудника
+type HistoryEntry struct {
+	ID        int       `json:"id"`
+	Direction string    `json:"direction"`
+	FromUser  string    `json:"fromUser"`
+	ToUser    string    `json:"toUser"`
+	Amount    int       `json:"amount"`
+	Message   string    `json:"message,omitempty"`
+	Public    bool      `json:"public"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// HistoryPage — страница истории. NextCursor пуст на последней странице
+type HistoryPage struct {
+	Items      []HistoryEntry `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// Роли сотрудников. auditor имеет доступ к административным данным только на чтение
+const (
+	RoleEmployee = "employee"
+	RoleAdmin    = "admin"
+	RoleAuditor  = "auditor"
+)
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleEmployee, RoleAdmin, RoleAuditor:
+		return true
+	}
+	return false
 }
 
 type Employee struct {
@@ -26,4 +111,5 @@ type Employee struct {
 	Name     string
 	Coins    int
 	Password string
+	Role     string
 }
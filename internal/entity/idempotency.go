@@ -0,0 +1,10 @@
+package entity
+
+// IdempotentResponse — сохранённый ответ на запрос с заголовком Idempotency-Key.
+// Нулевой Status означает, что запрос ещё выполняется
+type IdempotentResponse struct {
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
@@ -0,0 +1,76 @@
+package entity
+
+// Виды счетов журнала
+const (
+	AccountEmployee = "employee"
+	AccountStore    = "store"
+	AccountIssuance = "issuance"
+)
+
+// Виды проводок журнала
+const (
+	EntryIssuance = "issuance"
+	EntryTransfer = "transfer"
+	EntryPurchase = "purchase"
+)
+
+// LedgerAccount указывает счёт журнала. EmployeeID задаётся только для счетов сотрудников
+type LedgerAccount struct {
+	Kind       string
+	EmployeeID int
+}
+
+func EmployeeAccount(employeeID int) LedgerAccount {
+	return LedgerAccount{Kind: AccountEmployee, EmployeeID: employeeID}
+}
+
+var (
+	StoreAccount    = LedgerAccount{Kind: AccountStore}
+	IssuanceAccount = LedgerAccount{Kind: AccountIssuance}
+)
+
+// Posting — движение по счёту: положительная сумма увеличивает баланс, отрицательная уменьшает
+type Posting struct {
+	Account LedgerAccount
+	Amount  int
+}
+
+// JournalEntry — проводка журнала. Сумма движений проводки всегда равна нулю
+type JournalEntry struct {
+	Kind        string
+	Description string
+	Postings    []Posting
+}
+
+func (e JournalEntry) Balanced() bool {
+	if len(e.Postings) < 2 {
+		return false
+	}
+	total := 0
+	for _, posting := range e.Postings {
+		if posting.Amount == 0 {
+			return false
+		}
+		total += posting.Amount
+	}
+	return total == 0
+}
+
+// BalanceMismatch — расхождение баланса сотрудника в employees.coins с журналом
+type BalanceMismatch struct {
+	EmployeeID int `json:"employeeId"`
+	Cached     int `json:"cached"`
+	Ledger     int `json:"ledger"`
+}
+
+// LedgerReport — результат проверки инвариантов журнала. Монеты сохраняются,
+// если каждая проводка сбалансирована, выпущенные монеты равны сумме балансов
+// сотрудников и магазина, а балансы в employees.coins совпадают с журналом
+type LedgerReport struct {
+	Issued            int               `json:"issued"`
+	Employees         int               `json:"employees"`
+	Store             int               `json:"store"`
+	UnbalancedEntries []int             `json:"unbalancedEntries"`
+	BalanceMismatches []BalanceMismatch `json:"balanceMismatches"`
+	Conserved         bool              `json:"conserved"`
+}
@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+type Merch struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Price       int        `json:"price"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	Stock       int        `json:"stock"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
+}
+
+// MerchFilter задаёт выборку публичного каталога. Нулевые значения выборку не ограничивают
+type MerchFilter struct {
+	MinPrice int
+	MaxPrice int
+	Category string
+	// Sort — поле сортировки (price или name), префикс "-" означает сортировку по убыванию
+	Sort string
+}
+
+// StockMovement — запись журнала пополнений склада
+type StockMovement struct {
+	ID        int       `json:"id"`
+	Quantity  int       `json:"quantity"`
+	Employee  string    `json:"employee,omitempty"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// StockReport сверяет остаток товара с журналом пополнений и инвентарём
+// сотрудников: при согласованных данных Restocked = Stock + Sold
+type StockReport struct {
+	MerchID   int             `json:"merchId"`
+	Stock     int             `json:"stock"`
+	Restocked int             `json:"restocked"`
+	Sold      int             `json:"sold"`
+	Movements []StockMovement `json:"movements"`
+}
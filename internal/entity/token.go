@@ -0,0 +1,21 @@
+package entity
+
+import "time"
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RefreshToken struct {
+	EmployeeID int
+	Username   string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// TokenSubject — данные сотрудника, которые попадают в access-токен и сверяются при каждой проверке
+type TokenSubject struct {
+	Version int
+	Role    string
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"go.uber.org/zap"
+)
+
+const (
+	// Товар, снятый с продажи после добавления в корзину, остаётся в ней с нулевой ценой
+	queryListCart = "SELECT c.item, c.quantity, COALESCE(m.price, 0) FROM cart_items c LEFT JOIN merch m ON m.name = c.item AND m.deleted_at IS NULL " +
+		"WHERE c.employee_id = $1 ORDER BY c.item"
+	queryAddCartItem = "INSERT INTO cart_items (employee_id, item, quantity) VALUES ($1, $2, $3) " +
+		"ON CONFLICT (employee_id, item) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity " +
+		"WHERE cart_items.quantity + EXCLUDED.quantity <= $4"
+	queryRemoveCartItem = "DELETE FROM cart_items WHERE employee_id = $1 AND item = $2"
+	// Строки корзины блокируются в порядке названий товаров: повторное оформление
+	// той же корзины ждёт первого и видит её уже пустой
+	queryListCartForUpdate = "SELECT item, quantity FROM cart_items WHERE employee_id = $1 ORDER BY item FOR UPDATE"
+	queryClearCart         = "DELETE FROM cart_items WHERE employee_id = $1"
+)
+
+var ErrCartQuantityExceeded = errors.New("cart quantity exceeded")
+
+type CartRepository interface {
+	ListCart(ctx context.Context, employeeID int) ([]entity.CartItem, error)
+	// AddCartItem добавляет quantity единиц товара в корзину или возвращает
+	// ErrCartQuantityExceeded, если в позиции окажется больше maxQuantity
+	AddCartItem(ctx context.Context, employeeID int, itemName string, quantity, maxQuantity int) error
+	// RemoveCartItem удаляет позицию из корзины или возвращает pgx.ErrNoRows, если её нет
+	RemoveCartItem(ctx context.Context, employeeID int, itemName string) error
+	// ListCartForUpdateTx возвращает позиции корзины без цен и блокирует их до конца транзакции
+	ListCartForUpdateTx(ctx context.Context, tx pgx.Tx, employeeID int) ([]entity.CartItem, error)
+	ClearCartTx(ctx context.Context, tx pgx.Tx, employeeID int) error
+}
+
+type cartRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewCartRepository(db *pgxpool.Pool, logger *zap.Logger) CartRepository {
+	return &cartRepository{db: db, logger: logger}
+}
+
+func (r *cartRepository) ListCart(ctx context.Context, employeeID int) ([]entity.CartItem, error) {
+	rows, err := r.db.Query(ctx, queryListCart, employeeID)
+	if err != nil {
+		r.logger.Error("Error fetching cart", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []entity.CartItem{}
+	for rows.Next() {
+		var item entity.CartItem
+		if err := rows.Scan(&item.Item, &item.Quantity, &item.Price); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *cartRepository) AddCartItem(ctx context.Context, employeeID int, itemName string, quantity, maxQuantity int) error {
+	tag, err := r.db.Exec(ctx, queryAddCartItem, employeeID, itemName, quantity, maxQuantity)
+	if err != nil {
+		r.logger.Error("Error adding cart item", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCartQuantityExceeded
+	}
+	return nil
+}
+
+func (r *cartRepository) RemoveCartItem(ctx context.Context, employeeID int, itemName string) error {
+	tag, err := r.db.Exec(ctx, queryRemoveCartItem, employeeID, itemName)
+	if err != nil {
+		r.logger.Error("Error removing cart item", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *cartRepository) ListCartForUpdateTx(ctx context.Context, tx pgx.Tx, employeeID int) ([]entity.CartItem, error) {
+	rows, err := tx.Query(ctx, queryListCartForUpdate, employeeID)
+	if err != nil {
+		r.logger.Error("Error fetching cart in transaction", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []entity.CartItem{}
+	for rows.Next() {
+		var item entity.CartItem
+		if err := rows.Scan(&item.Item, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *cartRepository) ClearCartTx(ctx context.Context, tx pgx.Tx, employeeID int) error {
+	_, err := tx.Exec(ctx, queryClearCart, employeeID)
+	if err != nil {
+		r.logger.Error("Error clearing cart", zap.Error(err))
+	}
+	return err
+}
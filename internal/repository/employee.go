@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/qosmioo/merch-store/internal/entity"
@@ -10,33 +13,91 @@ import (
 )
 
 const (
-	queryGetEmployeeByID         = "SELECT id, name, coins FROM employees WHERE id = $1"
-	queryUpdateEmployeeCoins     = "UPDATE employees SET coins = $1 WHERE id = $2"
+	queryGetEmployeeByID  = "SELECT id, name, coins, role FROM employees WHERE id = $1"
+	queryLockEmployeeByID = "SELECT id, name, coins FROM employees WHERE id = $1 FOR UPDATE"
+	// Строки блокируются в порядке сортировки, поэтому транзакции, которым
+	// нужны одни и те же сотрудники, всегда ждут друг друга в одном порядке
+	queryLockEmployeesByIDs      = "SELECT id, name, coins FROM employees WHERE id = ANY($1) ORDER BY id FOR UPDATE"
 	queryGetEmployeeIDByUsername = "SELECT id FROM employees WHERE name = $1"
-	queryGetEmployeeByUsername   = "SELECT id, name, coins, password FROM employees WHERE name = $1"
+	queryGetEmployeeByUsername   = "SELECT id, name, coins, password, role FROM employees WHERE name = $1"
 	queryGetEmployeeInventory    = "SELECT type, quantity FROM inventory WHERE employee_id = $1"
-	queryGetEmployeeCoinHistoryR = "SELECT from_user_id, amount FROM transactions WHERE to_user_id = $1"
-	queryGetEmployeeCoinHistoryS = "SELECT to_user_id, amount FROM transactions WHERE from_user_id = $1"
-	queryRecordTransaction       = "INSERT INTO transactions (from_user_id, to_user_id, amount) VALUES ($1, $2, $3)"
-	queryAddToInventory          = "INSERT INTO inventory (employee_id, type, quantity) VALUES ($1, $2, 1) ON CONFLICT (employee_id, type) DO UPDATE SET quantity = inventory.quantity + 1"
-	queryCreateEmployee          = "INSERT INTO employees (name, password, coins) VALUES ($1, $2, $3)"
+	queryGetEmployeeCoinHistoryR = "SELECT e.name, t.amount, t.message, t.created_at FROM transactions t JOIN employees e ON e.id = t.from_user_id WHERE t.to_user_id = $1 ORDER BY t.created_at DESC, t.id DESC"
+	queryGetEmployeeCoinHistoryS = "SELECT e.name, t.amount, t.message, t.created_at FROM transactions t JOIN employees e ON e.id = t.to_user_id WHERE t.from_user_id = $1 ORDER BY t.created_at DESC, t.id DESC"
+	queryGetEmployeeHistory      = "SELECT t.id, CASE WHEN t.from_user_id = $1 THEN 'sent' ELSE 'received' END, sender.name, recipient.name, t.amount, t.message, t.public, t.created_at " +
+		"FROM transactions t JOIN employees sender ON sender.id = t.from_user_id JOIN employees recipient ON recipient.id = t.to_user_id " +
+		"WHERE ((t.from_user_id = $1 AND $2 <> 'received') OR (t.to_user_id = $1 AND $2 <> 'sent')) " +
+		"AND ($3::timestamp IS NULL OR t.created_at >= $3) AND ($4::timestamp IS NULL OR t.created_at < $4) " +
+		"AND ($5::timestamp IS NULL OR (t.created_at, t.id) < ($5, $6)) " +
+		"ORDER BY t.created_at DESC, t.id DESC LIMIT $7"
+	queryRecordTransaction      = "INSERT INTO transactions (from_user_id, to_user_id, amount, message, public) VALUES ($1, $2, $3, $4, $5)"
+	queryAddToInventory         = "INSERT INTO inventory (employee_id, type, quantity) VALUES ($1, $2, $3) ON CONFLICT (employee_id, type) DO UPDATE SET quantity = inventory.quantity + EXCLUDED.quantity"
+	queryGetMerchPrice          = "SELECT price FROM merch WHERE name = $1 AND deleted_at IS NULL"
+	queryUpdateEmployeePassword = "UPDATE employees SET password = $1 WHERE id = $2"
+	queryUpdateEmployeeRole     = "UPDATE employees SET role = $1 WHERE id = $2"
+	queryDecrementMerchStock    = "UPDATE merch SET stock = stock - $2 WHERE name = $1 AND deleted_at IS NULL AND stock >= $2"
+	queryListKudos              = "SELECT t.id, sender.name, recipient.name, t.amount, t.message, t.created_at FROM transactions t JOIN employees sender ON sender.id = t.from_user_id JOIN employees recipient ON recipient.id = t.to_user_id WHERE t.public ORDER BY t.created_at DESC, t.id DESC LIMIT $1"
+	queryRecordPurchase         = "INSERT INTO purchases (employee_id, item, price, quantity) VALUES ($1, $2, $3, $4)"
+	queryGetEmployeePurchases   = "SELECT item, price, quantity, created_at FROM purchases WHERE employee_id = $1 ORDER BY created_at DESC, id DESC"
+	queryCreateJournalEntry     = "INSERT INTO journal_entries (kind, description) VALUES ($1, $2) RETURNING id"
+	queryCreatePosting          = "INSERT INTO ledger_postings (entry_id, account_id, amount) SELECT $1, id, $2 FROM ledger_accounts WHERE kind = $3 AND employee_id IS NOT DISTINCT FROM $4"
+	queryAdjustEmployeeCoins    = "UPDATE employees SET coins = coins + $1 WHERE id = $2"
+	// Сотрудник создаётся вместе со счётом в журнале, а стартовый баланс
+	// оформляется проводкой со счёта эмиссии
+	queryCreateEmployee = "WITH employee AS (INSERT INTO employees (name, password, coins) VALUES ($1, $2, $3) RETURNING id, coins), " +
+		"account AS (INSERT INTO ledger_accounts (kind, employee_id) SELECT 'employee', id FROM employee RETURNING id), " +
+		"entry AS (INSERT INTO journal_entries (kind, description) SELECT 'issuance', 'starting balance' FROM employee WHERE coins > 0 RETURNING id), " +
+		"postings AS (INSERT INTO ledger_postings (entry_id, account_id, amount) SELECT entry.id, account.id, employee.coins FROM entry, account, employee " +
+		"UNION ALL SELECT entry.id, issuance.id, -employee.coins FROM entry, employee, ledger_accounts issuance WHERE issuance.kind = 'issuance') " +
+		"SELECT id FROM employee"
+)
+
+// uniqueViolationCode — код ошибки PostgreSQL при нарушении ограничения UNIQUE
+const uniqueViolationCode = "23505"
+
+var (
+	ErrEmployeeExists  = errors.New("employee already exists")
+	ErrOutOfStock      = errors.New("out of stock")
+	ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+	ErrAccountNotFound = errors.New("ledger account not found")
 )
 
 type EmployeeRepository interface {
-	GetEmployeeByID(employeeID int) (entity.Employee, error)
-	UpdateEmployeeCoins(employeeID, newAmount int) error
-	GetEmployeeIDByUsername(username string) (int, error)
-	GetEmployeeByUsername(username string) (entity.Employee, error)
-	GetEmployeeInventory(employeeID int) ([]entity.Inventory, error)
-	GetEmployeeCoinHistory(employeeID int) (entity.CoinHistory, error)
-	RecordTransaction(fromEmployeeID, toEmployeeID, amount int) error
-	AddToInventory(employeeID int, itemName string) error
-	GetMerchPrice(itemName string) (int, error)
-	CreateEmployee(employee entity.Employee) error
-	BeginTransaction() (pgx.Tx, error)
-	GetEmployeeByIDTx(tx pgx.Tx, employeeID int) (entity.Employee, error)
-	UpdateEmployeeCoinsTx(tx pgx.Tx, employeeID, newAmount int) error
-	RecordTransactionTx(tx pgx.Tx, fromEmployeeID, toEmployeeID, amount int) error
+	GetEmployeeByID(ctx context.Context, employeeID int) (entity.Employee, error)
+	GetEmployeeIDByUsername(ctx context.Context, username string) (int, error)
+	GetEmployeeByUsername(ctx context.Context, username string) (entity.Employee, error)
+	GetEmployeeInventory(ctx context.Context, employeeID int) ([]entity.Inventory, error)
+	// GetEmployeeCoinHistory возвращает все переводы сотрудника, начиная с последнего
+	GetEmployeeCoinHistory(ctx context.Context, employeeID int) (entity.CoinHistory, error)
+	// GetEmployeeHistory возвращает до limit переводов сотрудника после позиции after
+	// (nil — с самого нового), отсортированных по убыванию времени
+	GetEmployeeHistory(ctx context.Context, employeeID int, filter entity.HistoryFilter, after *entity.HistoryCursor, limit int) ([]entity.HistoryEntry, error)
+	// ListKudos возвращает последние опубликованные переводы
+	ListKudos(ctx context.Context, limit int) ([]entity.Kudos, error)
+	// GetEmployeePurchases возвращает все покупки сотрудника, начиная с
+	// последней. Список не ограничивается, так же как история переводов в /api/info
+	GetEmployeePurchases(ctx context.Context, employeeID int) ([]entity.Purchase, error)
+	RecordTransaction(ctx context.Context, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error
+	AddToInventory(ctx context.Context, employeeID int, itemName string, quantity int) error
+	GetMerchPrice(ctx context.Context, itemName string) (int, error)
+	CreateEmployee(ctx context.Context, employee entity.Employee) (int, error)
+	UpdateEmployeePassword(ctx context.Context, employeeID int, passwordHash string) error
+	UpdateEmployeeRole(ctx context.Context, employeeID int, role string) error
+	BeginTransaction(ctx context.Context) (pgx.Tx, error)
+	GetEmployeeByIDTx(ctx context.Context, tx pgx.Tx, employeeID int) (entity.Employee, error)
+	// LockEmployeesTx блокирует строки сотрудников в порядке возрастания ID до
+	// конца транзакции. Несуществующие ID пропускаются
+	LockEmployeesTx(ctx context.Context, tx pgx.Tx, employeeIDs ...int) ([]entity.Employee, error)
+	RecordTransactionTx(ctx context.Context, tx pgx.Tx, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error
+	GetMerchPriceTx(ctx context.Context, tx pgx.Tx, itemName string) (int, error)
+	AddToInventoryTx(ctx context.Context, tx pgx.Tx, employeeID int, itemName string, quantity int) error
+	// RecordPurchaseTx записывает покупку quantity единиц товара по цене price за единицу
+	RecordPurchaseTx(ctx context.Context, tx pgx.Tx, employeeID int, itemName string, price, quantity int) error
+	// DecrementMerchStockTx списывает quantity единиц товара со склада или
+	// возвращает ErrOutOfStock, если на складе их меньше
+	DecrementMerchStockTx(ctx context.Context, tx pgx.Tx, itemName string, quantity int) error
+	// PostJournalEntryTx записывает сбалансированную проводку в журнал и
+	// применяет её движения к балансам сотрудников в employees.coins
+	PostJournalEntryTx(ctx context.Context, tx pgx.Tx, entry entity.JournalEntry) error
 }
 
 type employeeRepository struct {
@@ -48,10 +109,10 @@ func NewEmployeeRepository(db *pgxpool.Pool, logger *zap.Logger) EmployeeReposit
 	return &employeeRepository{db: db, logger: logger}
 }
 
-func (r *employeeRepository) GetEmployeeByID(employeeID int) (entity.Employee, error) {
+func (r *employeeRepository) GetEmployeeByID(ctx context.Context, employeeID int) (entity.Employee, error) {
 	r.logger.Info("Fetching employee by ID", zap.Int("employeeID", employeeID))
 	var employee entity.Employee
-	err := r.db.QueryRow(context.Background(), queryGetEmployeeByID, employeeID).Scan(&employee.ID, &employee.Name, &employee.Coins)
+	err := r.db.QueryRow(ctx, queryGetEmployeeByID, employeeID).Scan(&employee.ID, &employee.Name, &employee.Coins, &employee.Role)
 	if err != nil {
 		r.logger.Error("Error fetching employee", zap.Error(err))
 		return entity.Employee{}, err
@@ -60,19 +121,10 @@ func (r *employeeRepository) GetEmployeeByID(employeeID int) (entity.Employee, e
 	return employee, nil
 }
 
-func (r *employeeRepository) UpdateEmployeeCoins(employeeID, newAmount int) error {
-	r.logger.Info("Updating employee coins", zap.Int("employeeID", employeeID), zap.Int("newAmount", newAmount))
-	_, err := r.db.Exec(context.Background(), queryUpdateEmployeeCoins, newAmount, employeeID)
-	if err != nil {
-		r.logger.Error("Error updating employee coins", zap.Error(err))
-	}
-	return err
-}
-
-func (r *employeeRepository) GetEmployeeIDByUsername(username string) (int, error) {
+func (r *employeeRepository) GetEmployeeIDByUsername(ctx context.Context, username string) (int, error) {
 	r.logger.Info("Fetching employee ID by username", zap.String("username", username))
 	var employeeID int
-	err := r.db.QueryRow(context.Background(), queryGetEmployeeIDByUsername, username).Scan(&employeeID)
+	err := r.db.QueryRow(ctx, queryGetEmployeeIDByUsername, username).Scan(&employeeID)
 	if err != nil {
 		r.logger.Error("Error fetching employee ID", zap.Error(err))
 		return 0, err
@@ -81,17 +133,17 @@ func (r *employeeRepository) GetEmployeeIDByUsername(username string) (int, erro
 	return employeeID, nil
 }
 
-func (r *employeeRepository) GetEmployeeByUsername(username string) (entity.Employee, error) {
+func (r *employeeRepository) GetEmployeeByUsername(ctx context.Context, username string) (entity.Employee, error) {
 	var employee entity.Employee
-	err := r.db.QueryRow(context.Background(), queryGetEmployeeByUsername, username).Scan(&employee.ID, &employee.Name, &employee.Coins, &employee.Password)
+	err := r.db.QueryRow(ctx, queryGetEmployeeByUsername, username).Scan(&employee.ID, &employee.Name, &employee.Coins, &employee.Password, &employee.Role)
 	if err != nil {
 		return entity.Employee{}, err
 	}
 	return employee, nil
 }
 
-func (r *employeeRepository) GetEmployeeInventory(employeeID int) ([]entity.Inventory, error) {
-	rows, err := r.db.Query(context.Background(), queryGetEmployeeInventory, employeeID)
+func (r *employeeRepository) GetEmployeeInventory(ctx context.Context, employeeID int) ([]entity.Inventory, error) {
+	rows, err := r.db.Query(ctx, queryGetEmployeeInventory, employeeID)
 	if err != nil {
 		return nil, err
 	}
@@ -108,10 +160,10 @@ func (r *employeeRepository) GetEmployeeInventory(employeeID int) ([]entity.Inve
 	return inventory, nil
 }
 
-func (r *employeeRepository) GetEmployeeCoinHistory(employeeID int) (entity.CoinHistory, error) {
+func (r *employeeRepository) GetEmployeeCoinHistory(ctx context.Context, employeeID int) (entity.CoinHistory, error) {
 	var history entity.CoinHistory
 
-	receivedRows, err := r.db.Query(context.Background(), queryGetEmployeeCoinHistoryR, employeeID)
+	receivedRows, err := r.db.Query(ctx, queryGetEmployeeCoinHistoryR, employeeID)
 	if err != nil {
 		return history, err
 	}
@@ -119,13 +171,13 @@ func (r *employeeRepository) GetEmployeeCoinHistory(employeeID int) (entity.Coin
 
 	for receivedRows.Next() {
 		var transaction entity.Transaction
-		if err := receivedRows.Scan(&transaction.UserID, &transaction.Amount); err != nil {
+		if err := receivedRows.Scan(&transaction.FromUser, &transaction.Amount, &transaction.Message, &transaction.CreatedAt); err != nil {
 			return history, err
 		}
 		history.Received = append(history.Received, transaction)
 	}
 
-	sentRows, err := r.db.Query(context.Background(), queryGetEmployeeCoinHistoryS, employeeID)
+	sentRows, err := r.db.Query(ctx, queryGetEmployeeCoinHistoryS, employeeID)
 	if err != nil {
 		return history, err
 	}
@@ -133,7 +185,7 @@ func (r *employeeRepository) GetEmployeeCoinHistory(employeeID int) (entity.Coin
 
 	for sentRows.Next() {
 		var transaction entity.Transaction
-		if err := sentRows.Scan(&transaction.UserID, &transaction.Amount); err != nil {
+		if err := sentRows.Scan(&transaction.ToUser, &transaction.Amount, &transaction.Message, &transaction.CreatedAt); err != nil {
 			return history, err
 		}
 		history.Sent = append(history.Sent, transaction)
@@ -142,21 +194,94 @@ func (r *employeeRepository) GetEmployeeCoinHistory(employeeID int) (entity.Coin
 	return history, nil
 }
 
-func (r *employeeRepository) RecordTransaction(fromEmployeeID, toEmployeeID, amount int) error {
-	_, err := r.db.Exec(context.Background(), queryRecordTransaction, fromEmployeeID, toEmployeeID, amount)
+func (r *employeeRepository) GetEmployeeHistory(ctx context.Context, employeeID int, filter entity.HistoryFilter, after *entity.HistoryCursor, limit int) ([]entity.HistoryEntry, error) {
+	// Нулевые границы передаются как NULL и не ограничивают выборку. Столбец
+	// created_at хранится без часового пояса в UTC
+	var from, to, afterCreatedAt *time.Time
+	var afterID int
+	if !filter.From.IsZero() {
+		value := filter.From.UTC()
+		from = &value
+	}
+	if !filter.To.IsZero() {
+		value := filter.To.UTC()
+		to = &value
+	}
+	if after != nil {
+		value := after.CreatedAt.UTC()
+		afterCreatedAt, afterID = &value, after.ID
+	}
+
+	rows, err := r.db.Query(ctx, queryGetEmployeeHistory, employeeID, filter.Direction, from, to, afterCreatedAt, afterID, limit)
+	if err != nil {
+		r.logger.Error("Error fetching employee history", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []entity.HistoryEntry{}
+	for rows.Next() {
+		var entry entity.HistoryEntry
+		if err := rows.Scan(&entry.ID, &entry.Direction, &entry.FromUser, &entry.ToUser, &entry.Amount, &entry.Message, &entry.Public, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+func (r *employeeRepository) ListKudos(ctx context.Context, limit int) ([]entity.Kudos, error) {
+	rows, err := r.db.Query(ctx, queryListKudos, limit)
+	if err != nil {
+		r.logger.Error("Error listing kudos", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	kudos := []entity.Kudos{}
+	for rows.Next() {
+		var item entity.Kudos
+		if err := rows.Scan(&item.ID, &item.FromUser, &item.ToUser, &item.Amount, &item.Message, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		kudos = append(kudos, item)
+	}
+	return kudos, rows.Err()
+}
+
+func (r *employeeRepository) GetEmployeePurchases(ctx context.Context, employeeID int) ([]entity.Purchase, error) {
+	rows, err := r.db.Query(ctx, queryGetEmployeePurchases, employeeID)
+	if err != nil {
+		r.logger.Error("Error fetching employee purchases", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	purchases := []entity.Purchase{}
+	for rows.Next() {
+		var purchase entity.Purchase
+		if err := rows.Scan(&purchase.Item, &purchase.Price, &purchase.Quantity, &purchase.CreatedAt); err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, purchase)
+	}
+	return purchases, rows.Err()
+}
+
+func (r *employeeRepository) RecordTransaction(ctx context.Context, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error {
+	_, err := r.db.Exec(ctx, queryRecordTransaction, fromEmployeeID, toEmployeeID, amount, memo.Message, memo.Public)
 	return err
 }
 
-func (r *employeeRepository) AddToInventory(employeeID int, itemName string) error {
-	_, err := r.db.Exec(context.Background(), queryAddToInventory, employeeID, itemName)
+func (r *employeeRepository) AddToInventory(ctx context.Context, employeeID int, itemName string, quantity int) error {
+	_, err := r.db.Exec(ctx, queryAddToInventory, employeeID, itemName, quantity)
 	return err
 }
 
-func (r *employeeRepository) GetMerchPrice(itemName string) (int, error) {
+func (r *employeeRepository) GetMerchPrice(ctx context.Context, itemName string) (int, error) {
 	r.logger.Info("Fetching merch price", zap.String("itemName", itemName))
-	query := "SELECT price FROM merch WHERE name = $1"
 	var price int
-	err := r.db.QueryRow(context.Background(), query, itemName).Scan(&price)
+	err := r.db.QueryRow(ctx, queryGetMerchPrice, itemName).Scan(&price)
 	if err != nil {
 		r.logger.Error("Error fetching merch price", zap.Error(err))
 		return 0, err
@@ -165,19 +290,45 @@ func (r *employeeRepository) GetMerchPrice(itemName string) (int, error) {
 	return price, nil
 }
 
-func (r *employeeRepository) CreateEmployee(employee entity.Employee) error {
-	_, err := r.db.Exec(context.Background(), queryCreateEmployee, employee.Name, employee.Password, employee.Coins)
+func (r *employeeRepository) CreateEmployee(ctx context.Context, employee entity.Employee) (int, error) {
+	var employeeID int
+	err := r.db.QueryRow(ctx, queryCreateEmployee, employee.Name, employee.Password, employee.Coins).Scan(&employeeID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return 0, ErrEmployeeExists
+	}
+	if err != nil {
+		return 0, err
+	}
+	return employeeID, nil
+}
+
+func (r *employeeRepository) UpdateEmployeePassword(ctx context.Context, employeeID int, passwordHash string) error {
+	r.logger.Info("Updating employee password hash", zap.Int("employeeID", employeeID))
+	_, err := r.db.Exec(ctx, queryUpdateEmployeePassword, passwordHash, employeeID)
+	if err != nil {
+		r.logger.Error("Error updating employee password hash", zap.Error(err))
+	}
 	return err
 }
 
-func (r *employeeRepository) BeginTransaction() (pgx.Tx, error) {
-	return r.db.Begin(context.Background())
+func (r *employeeRepository) UpdateEmployeeRole(ctx context.Context, employeeID int, role string) error {
+	r.logger.Info("Updating employee role", zap.Int("employeeID", employeeID), zap.String("role", role))
+	_, err := r.db.Exec(ctx, queryUpdateEmployeeRole, role, employeeID)
+	if err != nil {
+		r.logger.Error("Error updating employee role", zap.Error(err))
+	}
+	return err
 }
 
-func (r *employeeRepository) GetEmployeeByIDTx(tx pgx.Tx, employeeID int) (entity.Employee, error) {
+func (r *employeeRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
+	return r.db.Begin(ctx)
+}
+
+func (r *employeeRepository) GetEmployeeByIDTx(ctx context.Context, tx pgx.Tx, employeeID int) (entity.Employee, error) {
 	r.logger.Info("Fetching employee by ID in transaction", zap.Int("employeeID", employeeID))
 	var employee entity.Employee
-	err := tx.QueryRow(context.Background(), queryGetEmployeeByID, employeeID).Scan(&employee.ID, &employee.Name, &employee.Coins)
+	err := tx.QueryRow(ctx, queryLockEmployeeByID, employeeID).Scan(&employee.ID, &employee.Name, &employee.Coins)
 	if err != nil {
 		r.logger.Error("Error fetching employee in transaction", zap.Error(err))
 		return entity.Employee{}, err
@@ -185,16 +336,99 @@ func (r *employeeRepository) GetEmployeeByIDTx(tx pgx.Tx, employeeID int) (entit
 	return employee, nil
 }
 
-func (r *employeeRepository) UpdateEmployeeCoinsTx(tx pgx.Tx, employeeID, newAmount int) error {
-	r.logger.Info("Updating employee coins in transaction", zap.Int("employeeID", employeeID), zap.Int("newAmount", newAmount))
-	_, err := tx.Exec(context.Background(), queryUpdateEmployeeCoins, newAmount, employeeID)
+func (r *employeeRepository) LockEmployeesTx(ctx context.Context, tx pgx.Tx, employeeIDs ...int) ([]entity.Employee, error) {
+	rows, err := tx.Query(ctx, queryLockEmployeesByIDs, employeeIDs)
+	if err != nil {
+		r.logger.Error("Error locking employees", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var employees []entity.Employee
+	for rows.Next() {
+		var employee entity.Employee
+		if err := rows.Scan(&employee.ID, &employee.Name, &employee.Coins); err != nil {
+			return nil, err
+		}
+		employees = append(employees, employee)
+	}
+	return employees, rows.Err()
+}
+
+func (r *employeeRepository) RecordTransactionTx(ctx context.Context, tx pgx.Tx, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error {
+	_, err := tx.Exec(ctx, queryRecordTransaction, fromEmployeeID, toEmployeeID, amount, memo.Message, memo.Public)
+	return err
+}
+
+func (r *employeeRepository) GetMerchPriceTx(ctx context.Context, tx pgx.Tx, itemName string) (int, error) {
+	r.logger.Info("Fetching merch price in transaction", zap.String("itemName", itemName))
+	var price int
+	err := tx.QueryRow(ctx, queryGetMerchPrice, itemName).Scan(&price)
 	if err != nil {
-		r.logger.Error("Error updating employee coins in transaction", zap.Error(err))
+		r.logger.Error("Error fetching merch price in transaction", zap.Error(err))
+		return 0, err
 	}
+	return price, nil
+}
+
+func (r *employeeRepository) AddToInventoryTx(ctx context.Context, tx pgx.Tx, employeeID int, itemName string, quantity int) error {
+	_, err := tx.Exec(ctx, queryAddToInventory, employeeID, itemName, quantity)
 	return err
 }
 
-func (r *employeeRepository) RecordTransactionTx(tx pgx.Tx, fromEmployeeID, toEmployeeID, amount int) error {
-	_, err := tx.Exec(context.Background(), queryRecordTransaction, fromEmployeeID, toEmployeeID, amount)
+func (r *employeeRepository) RecordPurchaseTx(ctx context.Context, tx pgx.Tx, employeeID int, itemName string, price, quantity int) error {
+	_, err := tx.Exec(ctx, queryRecordPurchase, employeeID, itemName, price, quantity)
+	if err != nil {
+		r.logger.Error("Error recording purchase", zap.Error(err))
+	}
 	return err
 }
+
+func (r *employeeRepository) DecrementMerchStockTx(ctx context.Context, tx pgx.Tx, itemName string, quantity int) error {
+	tag, err := tx.Exec(ctx, queryDecrementMerchStock, itemName, quantity)
+	if err != nil {
+		r.logger.Error("Error decrementing merch stock", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOutOfStock
+	}
+	return nil
+}
+
+func (r *employeeRepository) PostJournalEntryTx(ctx context.Context, tx pgx.Tx, entry entity.JournalEntry) error {
+	r.logger.Info("Posting journal entry", zap.String("kind", entry.Kind), zap.Any("postings", entry.Postings))
+	if !entry.Balanced() {
+		return ErrUnbalancedEntry
+	}
+
+	var entryID int
+	if err := tx.QueryRow(ctx, queryCreateJournalEntry, entry.Kind, entry.Description).Scan(&entryID); err != nil {
+		r.logger.Error("Error creating journal entry", zap.Error(err))
+		return err
+	}
+	for _, posting := range entry.Postings {
+		// У счетов магазина и эмиссии employee_id равен NULL
+		var employeeID *int
+		if posting.Account.Kind == entity.AccountEmployee {
+			employeeID = &posting.Account.EmployeeID
+		}
+		tag, err := tx.Exec(ctx, queryCreatePosting, entryID, posting.Amount, posting.Account.Kind, employeeID)
+		if err != nil {
+			r.logger.Error("Error creating ledger posting", zap.Error(err))
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			r.logger.Error("Ledger account not found", zap.Any("account", posting.Account))
+			return ErrAccountNotFound
+		}
+		if employeeID == nil {
+			continue
+		}
+		if _, err := tx.Exec(ctx, queryAdjustEmployeeCoins, posting.Amount, *employeeID); err != nil {
+			r.logger.Error("Error adjusting employee coins", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"go.uber.org/zap"
+)
+
+const (
+	// Просроченный ключ, который ещё не удалила очистка, занимается заново. Так
+	// же занимается ключ, запрос по которому не завершился за время аренды:
+	// процесс, обрабатывавший его, упал, не успев ни сохранить ответ, ни освободить ключ
+	queryReserveIdempotencyKey = "INSERT INTO idempotency_keys (employee_id, key, fingerprint) VALUES ($1, $2, $3) " +
+		"ON CONFLICT (employee_id, key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = NULL, content_type = '', body = NULL, created_at = CURRENT_TIMESTAMP " +
+		"WHERE idempotency_keys.created_at < $4 OR (idempotency_keys.status IS NULL AND idempotency_keys.created_at < $5) RETURNING employee_id"
+	queryGetIdempotencyKey      = "SELECT fingerprint, COALESCE(status, 0), content_type, COALESCE(body, ''::bytea) FROM idempotency_keys WHERE employee_id = $1 AND key = $2"
+	queryCompleteIdempotencyKey = "UPDATE idempotency_keys SET status = $3, content_type = $4, body = $5 WHERE employee_id = $1 AND key = $2"
+	queryReleaseIdempotencyKey  = "DELETE FROM idempotency_keys WHERE employee_id = $1 AND key = $2 AND status IS NULL"
+	queryDeleteExpiredKeys      = "DELETE FROM idempotency_keys WHERE created_at < $1"
+)
+
+type IdempotencyRepository interface {
+	// ReserveKey занимает ключ для нового запроса. Возвращает false, если ключ
+	// уже занят и не истёк к моменту expiredBefore, а незавершённый запрос —
+	// к моменту staleBefore
+	ReserveKey(ctx context.Context, employeeID int, key, fingerprint string, expiredBefore, staleBefore time.Time) (bool, error)
+	GetKey(ctx context.Context, employeeID int, key string) (entity.IdempotentResponse, error)
+	CompleteKey(ctx context.Context, employeeID int, key string, response entity.IdempotentResponse) error
+	// ReleaseKey освобождает ключ запроса, который не удалось выполнить
+	ReleaseKey(ctx context.Context, employeeID int, key string) error
+	DeleteExpiredKeys(ctx context.Context, expiredBefore time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewIdempotencyRepository(db *pgxpool.Pool, logger *zap.Logger) IdempotencyRepository {
+	return &idempotencyRepository{db: db, logger: logger}
+}
+
+func (r *idempotencyRepository) ReserveKey(ctx context.Context, employeeID int, key, fingerprint string, expiredBefore, staleBefore time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, queryReserveIdempotencyKey, employeeID, key, fingerprint, expiredBefore.UTC(), staleBefore.UTC())
+	if err != nil {
+		r.logger.Error("Error reserving idempotency key", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *idempotencyRepository) GetKey(ctx context.Context, employeeID int, key string) (entity.IdempotentResponse, error) {
+	var response entity.IdempotentResponse
+	err := r.db.QueryRow(ctx, queryGetIdempotencyKey, employeeID, key).Scan(&response.Fingerprint, &response.Status, &response.ContentType, &response.Body)
+	if err != nil {
+		return entity.IdempotentResponse{}, err
+	}
+	return response, nil
+}
+
+func (r *idempotencyRepository) CompleteKey(ctx context.Context, employeeID int, key string, response entity.IdempotentResponse) error {
+	_, err := r.db.Exec(ctx, queryCompleteIdempotencyKey, employeeID, key, response.Status, response.ContentType, response.Body)
+	if err != nil {
+		r.logger.Error("Error storing idempotent response", zap.Error(err))
+	}
+	return err
+}
+
+func (r *idempotencyRepository) ReleaseKey(ctx context.Context, employeeID int, key string) error {
+	_, err := r.db.Exec(ctx, queryReleaseIdempotencyKey, employeeID, key)
+	if err != nil {
+		r.logger.Error("Error releasing idempotency key", zap.Error(err))
+	}
+	return err
+}
+
+func (r *idempotencyRepository) DeleteExpiredKeys(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, queryDeleteExpiredKeys, expiredBefore.UTC())
+	if err != nil {
+		r.logger.Error("Error deleting expired idempotency keys", zap.Error(err))
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
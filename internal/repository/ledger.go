@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"go.uber.org/zap"
+)
+
+const (
+	queryGetLedgerBalances     = "SELECT a.kind, COALESCE(SUM(p.amount), 0) FROM ledger_accounts a LEFT JOIN ledger_postings p ON p.account_id = a.id GROUP BY a.kind"
+	queryListUnbalancedEntries = "SELECT j.id FROM journal_entries j LEFT JOIN ledger_postings p ON p.entry_id = j.id GROUP BY j.id HAVING COUNT(p.id) < 2 OR COALESCE(SUM(p.amount), 0) <> 0 ORDER BY j.id"
+	queryListBalanceMismatches = "SELECT e.id, COALESCE(e.coins, 0), COALESCE(SUM(p.amount), 0) FROM employees e LEFT JOIN ledger_accounts a ON a.employee_id = e.id LEFT JOIN ledger_postings p ON p.account_id = a.id GROUP BY e.id, e.coins HAVING COALESCE(e.coins, 0) <> COALESCE(SUM(p.amount), 0) ORDER BY e.id"
+)
+
+type LedgerRepository interface {
+	// GetBalances возвращает суммарный баланс счетов каждого вида
+	GetBalances(ctx context.Context) (map[string]int, error)
+	// ListUnbalancedEntries возвращает проводки, движения которых не дают в сумме ноль
+	ListUnbalancedEntries(ctx context.Context) ([]int, error)
+	// ListBalanceMismatches возвращает сотрудников, чей баланс в employees.coins расходится с журналом
+	ListBalanceMismatches(ctx context.Context) ([]entity.BalanceMismatch, error)
+}
+
+type ledgerRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewLedgerRepository(db *pgxpool.Pool, logger *zap.Logger) LedgerRepository {
+	return &ledgerRepository{db: db, logger: logger}
+}
+
+func (r *ledgerRepository) GetBalances(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.Query(ctx, queryGetLedgerBalances)
+	if err != nil {
+		r.logger.Error("Error fetching ledger balances", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := map[string]int{}
+	for rows.Next() {
+		var (
+			kind    string
+			balance int
+		)
+		if err := rows.Scan(&kind, &balance); err != nil {
+			return nil, err
+		}
+		balances[kind] = balance
+	}
+	return balances, rows.Err()
+}
+
+func (r *ledgerRepository) ListUnbalancedEntries(ctx context.Context) ([]int, error) {
+	rows, err := r.db.Query(ctx, queryListUnbalancedEntries)
+	if err != nil {
+		r.logger.Error("Error listing unbalanced journal entries", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []int{}
+	for rows.Next() {
+		var entryID int
+		if err := rows.Scan(&entryID); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entryID)
+	}
+	return entries, rows.Err()
+}
+
+func (r *ledgerRepository) ListBalanceMismatches(ctx context.Context) ([]entity.BalanceMismatch, error) {
+	rows, err := r.db.Query(ctx, queryListBalanceMismatches)
+	if err != nil {
+		r.logger.Error("Error listing balance mismatches", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	mismatches := []entity.BalanceMismatch{}
+	for rows.Next() {
+		var mismatch entity.BalanceMismatch
+		if err := rows.Scan(&mismatch.EmployeeID, &mismatch.Cached, &mismatch.Ledger); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, mismatch)
+	}
+	return mismatches, rows.Err()
+}
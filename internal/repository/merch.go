@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"go.uber.org/zap"
+)
+
+const (
+	queryListMerch       = "SELECT id, name, price, description, category, stock, deleted_at FROM merch WHERE deleted_at IS NULL ORDER BY id"
+	queryListAllMerch    = "SELECT id, name, price, description, category, stock, deleted_at FROM merch ORDER BY id"
+	queryGetMerchByID    = "SELECT id, name, price, description, category, stock, deleted_at FROM merch WHERE id = $1"
+	queryUpdateMerch     = "UPDATE merch SET price = $1, description = $2, category = $3 WHERE id = $4 AND deleted_at IS NULL"
+	querySoftDeleteMerch = "UPDATE merch SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL"
+	queryRestockMerch    = "WITH restocked AS (UPDATE merch SET stock = stock + $2 WHERE id = $1 AND deleted_at IS NULL RETURNING id) INSERT INTO stock_movements (merch_id, quantity, employee_id, comment) SELECT id, $2, $3, $4 FROM restocked"
+	queryListMovements   = "SELECT sm.id, sm.quantity, COALESCE(e.name, ''), sm.comment, sm.created_at FROM stock_movements sm LEFT JOIN employees e ON e.id = sm.employee_id WHERE sm.merch_id = $1 ORDER BY sm.id"
+	queryGetMerchSold    = "SELECT COALESCE(SUM(i.quantity), 0) FROM inventory i JOIN merch m ON m.name = i.type WHERE m.id = $1"
+	// Снятый с продажи товар с тем же названием возвращается в каталог, активный — даёт конфликт
+	queryCreateMerch = "INSERT INTO merch (name, price, description, category) VALUES ($1, $2, $3, $4) ON CONFLICT (name) DO UPDATE SET price = EXCLUDED.price, description = EXCLUDED.description, category = EXCLUDED.category, deleted_at = NULL WHERE merch.deleted_at IS NOT NULL RETURNING id"
+)
+
+var ErrMerchExists = errors.New("merch already exists")
+
+type MerchRepository interface {
+	// ListMerch возвращает товары в продаже, а с includeDeleted — и снятые с продажи
+	ListMerch(ctx context.Context, includeDeleted bool) ([]entity.Merch, error)
+	GetMerchByID(ctx context.Context, merchID int) (entity.Merch, error)
+	CreateMerch(ctx context.Context, merch entity.Merch) (int, error)
+	// UpdateMerch и DeleteMerch возвращают pgx.ErrNoRows, если товара нет или он снят с продажи
+	UpdateMerch(ctx context.Context, merch entity.Merch) error
+	DeleteMerch(ctx context.Context, merchID int) error
+	// RestockMerch атомарно увеличивает остаток и записывает пополнение в журнал
+	RestockMerch(ctx context.Context, merchID, quantity, employeeID int, comment string) error
+	ListStockMovements(ctx context.Context, merchID int) ([]entity.StockMovement, error)
+	// GetMerchSold возвращает количество товара в инвентаре всех сотрудников
+	GetMerchSold(ctx context.Context, merchID int) (int, error)
+}
+
+type merchRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewMerchRepository(db *pgxpool.Pool, logger *zap.Logger) MerchRepository {
+	return &merchRepository{db: db, logger: logger}
+}
+
+func (r *merchRepository) ListMerch(ctx context.Context, includeDeleted bool) ([]entity.Merch, error) {
+	query := queryListMerch
+	if includeDeleted {
+		query = queryListAllMerch
+	}
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		r.logger.Error("Error listing merch", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	merch := []entity.Merch{}
+	for rows.Next() {
+		var item entity.Merch
+		if err := rows.Scan(&item.ID, &item.Name, &item.Price, &item.Description, &item.Category, &item.Stock, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		merch = append(merch, item)
+	}
+	return merch, rows.Err()
+}
+
+func (r *merchRepository) GetMerchByID(ctx context.Context, merchID int) (entity.Merch, error) {
+	var item entity.Merch
+	err := r.db.QueryRow(ctx, queryGetMerchByID, merchID).Scan(&item.ID, &item.Name, &item.Price, &item.Description, &item.Category, &item.Stock, &item.DeletedAt)
+	if err != nil {
+		return entity.Merch{}, err
+	}
+	return item, nil
+}
+
+func (r *merchRepository) CreateMerch(ctx context.Context, merch entity.Merch) (int, error) {
+	r.logger.Info("Creating merch", zap.String("name", merch.Name), zap.Int("price", merch.Price))
+	var merchID int
+	err := r.db.QueryRow(ctx, queryCreateMerch, merch.Name, merch.Price, merch.Description, merch.Category).Scan(&merchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrMerchExists
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return 0, ErrMerchExists
+	}
+	if err != nil {
+		r.logger.Error("Error creating merch", zap.Error(err))
+		return 0, err
+	}
+	return merchID, nil
+}
+
+func (r *merchRepository) UpdateMerch(ctx context.Context, merch entity.Merch) error {
+	r.logger.Info("Updating merch", zap.Int("merchID", merch.ID), zap.Int("price", merch.Price))
+	tag, err := r.db.Exec(ctx, queryUpdateMerch, merch.Price, merch.Description, merch.Category, merch.ID)
+	if err != nil {
+		r.logger.Error("Error updating merch", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *merchRepository) DeleteMerch(ctx context.Context, merchID int) error {
+	r.logger.Info("Deleting merch", zap.Int("merchID", merchID))
+	tag, err := r.db.Exec(ctx, querySoftDeleteMerch, merchID)
+	if err != nil {
+		r.logger.Error("Error deleting merch", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *merchRepository) RestockMerch(ctx context.Context, merchID, quantity, employeeID int, comment string) error {
+	r.logger.Info("Restocking merch", zap.Int("merchID", merchID), zap.Int("quantity", quantity), zap.Int("employeeID", employeeID))
+	tag, err := r.db.Exec(ctx, queryRestockMerch, merchID, quantity, employeeID, comment)
+	if err != nil {
+		r.logger.Error("Error restocking merch", zap.Error(err))
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *merchRepository) ListStockMovements(ctx context.Context, merchID int) ([]entity.StockMovement, error) {
+	rows, err := r.db.Query(ctx, queryListMovements, merchID)
+	if err != nil {
+		r.logger.Error("Error listing stock movements", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []entity.StockMovement{}
+	for rows.Next() {
+		var movement entity.StockMovement
+		if err := rows.Scan(&movement.ID, &movement.Quantity, &movement.Employee, &movement.Comment, &movement.CreatedAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, movement)
+	}
+	return movements, rows.Err()
+}
+
+func (r *merchRepository) GetMerchSold(ctx context.Context, merchID int) (int, error) {
+	var sold int
+	err := r.db.QueryRow(ctx, queryGetMerchSold, merchID).Scan(&sold)
+	if err != nil {
+		r.logger.Error("Error counting sold merch", zap.Error(err))
+		return 0, err
+	}
+	return sold, nil
+}
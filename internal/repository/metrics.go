@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredConns = prometheus.NewDesc("db_pool_acquired_connections", "Connections currently in use.", nil, nil)
+	poolIdleConns     = prometheus.NewDesc("db_pool_idle_connections", "Idle connections in the pool.", nil, nil)
+	poolTotalConns    = prometheus.NewDesc("db_pool_total_connections", "Open connections, including ones being established.", nil, nil)
+	poolMaxConns      = prometheus.NewDesc("db_pool_max_connections", "Maximum size of the pool.", nil, nil)
+	poolAcquires      = prometheus.NewDesc("db_pool_acquires_total", "Successful connection acquisitions.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc("db_pool_empty_acquires_total", "Acquisitions that had to wait because the pool was empty.", nil, nil)
+	poolAcquireWait   = prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Total time spent acquiring connections.", nil, nil)
+)
+
+// poolCollector публикует статистику пула соединений. Значения читаются из
+// pgxpool.Stat в момент запроса /metrics
+type poolCollector struct {
+	db *pgxpool.Pool
+}
+
+func NewPoolCollector(db *pgxpool.Pool) prometheus.Collector {
+	return &poolCollector{db: db}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolAcquireWait
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.db.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireWait, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
@@ -5,6 +5,7 @@
 package repository
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,205 +37,342 @@ func (m *MockEmployeeRepository) EXPECT() *MockEmployeeRepositoryMockRecorder {
 }
 
 // AddToInventory mocks base method.
-func (m *MockEmployeeRepository) AddToInventory(employeeID int, itemName string) error {
+func (m *MockEmployeeRepository) AddToInventory(ctx context.Context, employeeID int, itemName string, quantity int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddToInventory", employeeID, itemName)
+	ret := m.ctrl.Call(m, "AddToInventory", ctx, employeeID, itemName, quantity)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // AddToInventory indicates an expected call of AddToInventory.
-func (mr *MockEmployeeRepositoryMockRecorder) AddToInventory(employeeID, itemName interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) AddToInventory(ctx, employeeID, itemName, quantity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToInventory", reflect.TypeOf((*MockEmployeeRepository)(nil).AddToInventory), employeeID, itemName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToInventory", reflect.TypeOf((*MockEmployeeRepository)(nil).AddToInventory), ctx, employeeID, itemName, quantity)
+}
+
+// AddToInventoryTx mocks base method.
+func (m *MockEmployeeRepository) AddToInventoryTx(ctx context.Context, tx pgx.Tx, employeeID int, itemName string, quantity int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddToInventoryTx", ctx, tx, employeeID, itemName, quantity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddToInventoryTx indicates an expected call of AddToInventoryTx.
+func (mr *MockEmployeeRepositoryMockRecorder) AddToInventoryTx(ctx, tx, employeeID, itemName, quantity interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddToInventoryTx", reflect.TypeOf((*MockEmployeeRepository)(nil).AddToInventoryTx), ctx, tx, employeeID, itemName, quantity)
 }
 
 // BeginTransaction mocks base method.
-func (m *MockEmployeeRepository) BeginTransaction() (pgx.Tx, error) {
+func (m *MockEmployeeRepository) BeginTransaction(ctx context.Context) (pgx.Tx, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BeginTransaction")
+	ret := m.ctrl.Call(m, "BeginTransaction", ctx)
 	ret0, _ := ret[0].(pgx.Tx)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // BeginTransaction indicates an expected call of BeginTransaction.
-func (mr *MockEmployeeRepositoryMockRecorder) BeginTransaction() *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) BeginTransaction(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTransaction", reflect.TypeOf((*MockEmployeeRepository)(nil).BeginTransaction))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginTransaction", reflect.TypeOf((*MockEmployeeRepository)(nil).BeginTransaction), ctx)
 }
 
 // CreateEmployee mocks base method.
-func (m *MockEmployeeRepository) CreateEmployee(employee entity.Employee) error {
+func (m *MockEmployeeRepository) CreateEmployee(ctx context.Context, employee entity.Employee) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEmployee", employee)
+	ret := m.ctrl.Call(m, "CreateEmployee", ctx, employee)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEmployee indicates an expected call of CreateEmployee.
+func (mr *MockEmployeeRepositoryMockRecorder) CreateEmployee(ctx, employee interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmployee", reflect.TypeOf((*MockEmployeeRepository)(nil).CreateEmployee), ctx, employee)
+}
+
+// DecrementMerchStockTx mocks base method.
+func (m *MockEmployeeRepository) DecrementMerchStockTx(ctx context.Context, tx pgx.Tx, itemName string, quantity int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrementMerchStockTx", ctx, tx, itemName, quantity)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// CreateEmployee indicates an expected call of CreateEmployee.
-func (mr *MockEmployeeRepositoryMockRecorder) CreateEmployee(employee interface{}) *gomock.Call {
+// DecrementMerchStockTx indicates an expected call of DecrementMerchStockTx.
+func (mr *MockEmployeeRepositoryMockRecorder) DecrementMerchStockTx(ctx, tx, itemName, quantity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmployee", reflect.TypeOf((*MockEmployeeRepository)(nil).CreateEmployee), employee)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrementMerchStockTx", reflect.TypeOf((*MockEmployeeRepository)(nil).DecrementMerchStockTx), ctx, tx, itemName, quantity)
 }
 
 // GetEmployeeByID mocks base method.
-func (m *MockEmployeeRepository) GetEmployeeByID(employeeID int) (entity.Employee, error) {
+func (m *MockEmployeeRepository) GetEmployeeByID(ctx context.Context, employeeID int) (entity.Employee, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEmployeeByID", employeeID)
+	ret := m.ctrl.Call(m, "GetEmployeeByID", ctx, employeeID)
 	ret0, _ := ret[0].(entity.Employee)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEmployeeByID indicates an expected call of GetEmployeeByID.
-func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeByID(employeeID interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeByID(ctx, employeeID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeByID", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeByID), employeeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeByID", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeByID), ctx, employeeID)
 }
 
 // GetEmployeeByIDTx mocks base method.
-func (m *MockEmployeeRepository) GetEmployeeByIDTx(tx pgx.Tx, employeeID int) (entity.Employee, error) {
+func (m *MockEmployeeRepository) GetEmployeeByIDTx(ctx context.Context, tx pgx.Tx, employeeID int) (entity.Employee, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEmployeeByIDTx", tx, employeeID)
+	ret := m.ctrl.Call(m, "GetEmployeeByIDTx", ctx, tx, employeeID)
 	ret0, _ := ret[0].(entity.Employee)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEmployeeByIDTx indicates an expected call of GetEmployeeByIDTx.
-func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeByIDTx(tx, employeeID interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeByIDTx(ctx, tx, employeeID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeByIDTx", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeByIDTx), tx, employeeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeByIDTx", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeByIDTx), ctx, tx, employeeID)
 }
 
 // GetEmployeeByUsername mocks base method.
-func (m *MockEmployeeRepository) GetEmployeeByUsername(username string) (entity.Employee, error) {
+func (m *MockEmployeeRepository) GetEmployeeByUsername(ctx context.Context, username string) (entity.Employee, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEmployeeByUsername", username)
+	ret := m.ctrl.Call(m, "GetEmployeeByUsername", ctx, username)
 	ret0, _ := ret[0].(entity.Employee)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEmployeeByUsername indicates an expected call of GetEmployeeByUsername.
-func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeByUsername(username interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeByUsername(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeByUsername", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeByUsername), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeByUsername", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeByUsername), ctx, username)
 }
 
 // GetEmployeeCoinHistory mocks base method.
-func (m *MockEmployeeRepository) GetEmployeeCoinHistory(employeeID int) (entity.CoinHistory, error) {
+func (m *MockEmployeeRepository) GetEmployeeCoinHistory(ctx context.Context, employeeID int) (entity.CoinHistory, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEmployeeCoinHistory", employeeID)
+	ret := m.ctrl.Call(m, "GetEmployeeCoinHistory", ctx, employeeID)
 	ret0, _ := ret[0].(entity.CoinHistory)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEmployeeCoinHistory indicates an expected call of GetEmployeeCoinHistory.
-func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeCoinHistory(employeeID interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeCoinHistory(ctx, employeeID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeCoinHistory", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeCoinHistory), employeeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeCoinHistory", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeCoinHistory), ctx, employeeID)
+}
+
+// GetEmployeeHistory mocks base method.
+func (m *MockEmployeeRepository) GetEmployeeHistory(ctx context.Context, employeeID int, filter entity.HistoryFilter, after *entity.HistoryCursor, limit int) ([]entity.HistoryEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEmployeeHistory", ctx, employeeID, filter, after, limit)
+	ret0, _ := ret[0].([]entity.HistoryEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEmployeeHistory indicates an expected call of GetEmployeeHistory.
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeHistory(ctx, employeeID, filter, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeHistory", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeHistory), ctx, employeeID, filter, after, limit)
 }
 
 // GetEmployeeIDByUsername mocks base method.
-func (m *MockEmployeeRepository) GetEmployeeIDByUsername(username string) (int, error) {
+func (m *MockEmployeeRepository) GetEmployeeIDByUsername(ctx context.Context, username string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEmployeeIDByUsername", username)
+	ret := m.ctrl.Call(m, "GetEmployeeIDByUsername", ctx, username)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEmployeeIDByUsername indicates an expected call of GetEmployeeIDByUsername.
-func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeIDByUsername(username interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeIDByUsername(ctx, username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeIDByUsername", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeIDByUsername), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeIDByUsername", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeIDByUsername), ctx, username)
 }
 
 // GetEmployeeInventory mocks base method.
-func (m *MockEmployeeRepository) GetEmployeeInventory(employeeID int) ([]entity.Inventory, error) {
+func (m *MockEmployeeRepository) GetEmployeeInventory(ctx context.Context, employeeID int) ([]entity.Inventory, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEmployeeInventory", employeeID)
+	ret := m.ctrl.Call(m, "GetEmployeeInventory", ctx, employeeID)
 	ret0, _ := ret[0].([]entity.Inventory)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEmployeeInventory indicates an expected call of GetEmployeeInventory.
-func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeInventory(employeeID interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeeInventory(ctx, employeeID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeInventory", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeInventory), employeeID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeeInventory", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeeInventory), ctx, employeeID)
+}
+
+// GetEmployeePurchases mocks base method.
+func (m *MockEmployeeRepository) GetEmployeePurchases(ctx context.Context, employeeID int) ([]entity.Purchase, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEmployeePurchases", ctx, employeeID)
+	ret0, _ := ret[0].([]entity.Purchase)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEmployeePurchases indicates an expected call of GetEmployeePurchases.
+func (mr *MockEmployeeRepositoryMockRecorder) GetEmployeePurchases(ctx, employeeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmployeePurchases", reflect.TypeOf((*MockEmployeeRepository)(nil).GetEmployeePurchases), ctx, employeeID)
 }
 
 // GetMerchPrice mocks base method.
-func (m *MockEmployeeRepository) GetMerchPrice(itemName string) (int, error) {
+func (m *MockEmployeeRepository) GetMerchPrice(ctx context.Context, itemName string) (int, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetMerchPrice", itemName)
+	ret := m.ctrl.Call(m, "GetMerchPrice", ctx, itemName)
 	ret0, _ := ret[0].(int)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetMerchPrice indicates an expected call of GetMerchPrice.
-func (mr *MockEmployeeRepositoryMockRecorder) GetMerchPrice(itemName interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) GetMerchPrice(ctx, itemName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMerchPrice", reflect.TypeOf((*MockEmployeeRepository)(nil).GetMerchPrice), ctx, itemName)
+}
+
+// GetMerchPriceTx mocks base method.
+func (m *MockEmployeeRepository) GetMerchPriceTx(ctx context.Context, tx pgx.Tx, itemName string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMerchPriceTx", ctx, tx, itemName)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMerchPriceTx indicates an expected call of GetMerchPriceTx.
+func (mr *MockEmployeeRepositoryMockRecorder) GetMerchPriceTx(ctx, tx, itemName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMerchPriceTx", reflect.TypeOf((*MockEmployeeRepository)(nil).GetMerchPriceTx), ctx, tx, itemName)
+}
+
+// ListKudos mocks base method.
+func (m *MockEmployeeRepository) ListKudos(ctx context.Context, limit int) ([]entity.Kudos, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListKudos", ctx, limit)
+	ret0, _ := ret[0].([]entity.Kudos)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListKudos indicates an expected call of ListKudos.
+func (mr *MockEmployeeRepositoryMockRecorder) ListKudos(ctx, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListKudos", reflect.TypeOf((*MockEmployeeRepository)(nil).ListKudos), ctx, limit)
+}
+
+// LockEmployeesTx mocks base method.
+func (m *MockEmployeeRepository) LockEmployeesTx(ctx context.Context, tx pgx.Tx, employeeIDs ...int) ([]entity.Employee, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx, tx}
+	for _, a := range employeeIDs {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "LockEmployeesTx", varargs...)
+	ret0, _ := ret[0].([]entity.Employee)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockEmployeesTx indicates an expected call of LockEmployeesTx.
+func (mr *MockEmployeeRepositoryMockRecorder) LockEmployeesTx(ctx, tx interface{}, employeeIDs ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx, tx}, employeeIDs...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockEmployeesTx", reflect.TypeOf((*MockEmployeeRepository)(nil).LockEmployeesTx), varargs...)
+}
+
+// PostJournalEntryTx mocks base method.
+func (m *MockEmployeeRepository) PostJournalEntryTx(ctx context.Context, tx pgx.Tx, entry entity.JournalEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostJournalEntryTx", ctx, tx, entry)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PostJournalEntryTx indicates an expected call of PostJournalEntryTx.
+func (mr *MockEmployeeRepositoryMockRecorder) PostJournalEntryTx(ctx, tx, entry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostJournalEntryTx", reflect.TypeOf((*MockEmployeeRepository)(nil).PostJournalEntryTx), ctx, tx, entry)
+}
+
+// RecordPurchaseTx mocks base method.
+func (m *MockEmployeeRepository) RecordPurchaseTx(ctx context.Context, tx pgx.Tx, employeeID int, itemName string, price, quantity int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordPurchaseTx", ctx, tx, employeeID, itemName, price, quantity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordPurchaseTx indicates an expected call of RecordPurchaseTx.
+func (mr *MockEmployeeRepositoryMockRecorder) RecordPurchaseTx(ctx, tx, employeeID, itemName, price, quantity interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMerchPrice", reflect.TypeOf((*MockEmployeeRepository)(nil).GetMerchPrice), itemName)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordPurchaseTx", reflect.TypeOf((*MockEmployeeRepository)(nil).RecordPurchaseTx), ctx, tx, employeeID, itemName, price, quantity)
 }
 
 // RecordTransaction mocks base method.
-func (m *MockEmployeeRepository) RecordTransaction(fromEmployeeID, toEmployeeID, amount int) error {
+func (m *MockEmployeeRepository) RecordTransaction(ctx context.Context, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RecordTransaction", fromEmployeeID, toEmployeeID, amount)
+	ret := m.ctrl.Call(m, "RecordTransaction", ctx, fromEmployeeID, toEmployeeID, amount, memo)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RecordTransaction indicates an expected call of RecordTransaction.
-func (mr *MockEmployeeRepositoryMockRecorder) RecordTransaction(fromEmployeeID, toEmployeeID, amount interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) RecordTransaction(ctx, fromEmployeeID, toEmployeeID, amount, memo interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTransaction", reflect.TypeOf((*MockEmployeeRepository)(nil).RecordTransaction), fromEmployeeID, toEmployeeID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTransaction", reflect.TypeOf((*MockEmployeeRepository)(nil).RecordTransaction), ctx, fromEmployeeID, toEmployeeID, amount, memo)
 }
 
 // RecordTransactionTx mocks base method.
-func (m *MockEmployeeRepository) RecordTransactionTx(tx pgx.Tx, fromEmployeeID, toEmployeeID, amount int) error {
+func (m *MockEmployeeRepository) RecordTransactionTx(ctx context.Context, tx pgx.Tx, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RecordTransactionTx", tx, fromEmployeeID, toEmployeeID, amount)
+	ret := m.ctrl.Call(m, "RecordTransactionTx", ctx, tx, fromEmployeeID, toEmployeeID, amount, memo)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RecordTransactionTx indicates an expected call of RecordTransactionTx.
-func (mr *MockEmployeeRepositoryMockRecorder) RecordTransactionTx(tx, fromEmployeeID, toEmployeeID, amount interface{}) *gomock.Call {
+func (mr *MockEmployeeRepositoryMockRecorder) RecordTransactionTx(ctx, tx, fromEmployeeID, toEmployeeID, amount, memo interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTransactionTx", reflect.TypeOf((*MockEmployeeRepository)(nil).RecordTransactionTx), tx, fromEmployeeID, toEmployeeID, amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordTransactionTx", reflect.TypeOf((*MockEmployeeRepository)(nil).RecordTransactionTx), ctx, tx, fromEmployeeID, toEmployeeID, amount, memo)
 }
 
-// UpdateEmployeeCoins mocks base method.
-func (m *MockEmployeeRepository) UpdateEmployeeCoins(employeeID, newAmount int) error {
+// UpdateEmployeePassword mocks base method.
+func (m *MockEmployeeRepository) UpdateEmployeePassword(ctx context.Context, employeeID int, passwordHash string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateEmployeeCoins", employeeID, newAmount)
+	ret := m.ctrl.Call(m, "UpdateEmployeePassword", ctx, employeeID, passwordHash)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateEmployeeCoins indicates an expected call of UpdateEmployeeCoins.
-func (mr *MockEmployeeRepositoryMockRecorder) UpdateEmployeeCoins(employeeID, newAmount interface{}) *gomock.Call {
+// UpdateEmployeePassword indicates an expected call of UpdateEmployeePassword.
+func (mr *MockEmployeeRepositoryMockRecorder) UpdateEmployeePassword(ctx, employeeID, passwordHash interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmployeeCoins", reflect.TypeOf((*MockEmployeeRepository)(nil).UpdateEmployeeCoins), employeeID, newAmount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmployeePassword", reflect.TypeOf((*MockEmployeeRepository)(nil).UpdateEmployeePassword), ctx, employeeID, passwordHash)
 }
 
-// UpdateEmployeeCoinsTx mocks base method.
-func (m *MockEmployeeRepository) UpdateEmployeeCoinsTx(tx pgx.Tx, employeeID, newAmount int) error {
+// UpdateEmployeeRole mocks base method.
+func (m *MockEmployeeRepository) UpdateEmployeeRole(ctx context.Context, employeeID int, role string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateEmployeeCoinsTx", tx, employeeID, newAmount)
+	ret := m.ctrl.Call(m, "UpdateEmployeeRole", ctx, employeeID, role)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// UpdateEmployeeCoinsTx indicates an expected call of UpdateEmployeeCoinsTx.
-func (mr *MockEmployeeRepositoryMockRecorder) UpdateEmployeeCoinsTx(tx, employeeID, newAmount interface{}) *gomock.Call {
+// UpdateEmployeeRole indicates an expected call of UpdateEmployeeRole.
+func (mr *MockEmployeeRepositoryMockRecorder) UpdateEmployeeRole(ctx, employeeID, role interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmployeeCoinsTx", reflect.TypeOf((*MockEmployeeRepository)(nil).UpdateEmployeeCoinsTx), tx, employeeID, newAmount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEmployeeRole", reflect.TypeOf((*MockEmployeeRepository)(nil).UpdateEmployeeRole), ctx, employeeID, role)
 }
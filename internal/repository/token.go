@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"go.uber.org/zap"
+)
+
+const (
+	queryCreateRefreshToken    = "INSERT INTO refresh_tokens (employee_id, token_hash, expires_at) VALUES ($1, $2, $3)"
+	queryConsumeRefreshToken   = "UPDATE refresh_tokens rt SET revoked_at = NOW() FROM employees e WHERE rt.token_hash = $1 AND rt.revoked_at IS NULL AND rt.expires_at > NOW() AND e.id = rt.employee_id RETURNING rt.employee_id, e.name, rt.expires_at, rt.revoked_at"
+	queryGetRefreshToken       = "SELECT rt.employee_id, e.name, rt.expires_at, rt.revoked_at FROM refresh_tokens rt JOIN employees e ON e.id = rt.employee_id WHERE rt.token_hash = $1"
+	queryRevokeRefreshTokens   = "UPDATE refresh_tokens SET revoked_at = NOW() WHERE employee_id = $1 AND revoked_at IS NULL"
+	queryGetTokenSubject       = "SELECT token_version, role FROM employees WHERE name = $1"
+	queryIncrementTokenVersion = "UPDATE employees SET token_version = token_version + 1 WHERE name = $1"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, employeeID int, tokenHash string, expiresAt time.Time) error
+	// ConsumeRefreshToken атомарно отзывает активный токен и возвращает его;
+	// если токен не найден, истёк или уже отозван, возвращается pgx.ErrNoRows
+	ConsumeRefreshToken(ctx context.Context, tokenHash string) (entity.RefreshToken, error)
+	GetRefreshToken(ctx context.Context, tokenHash string) (entity.RefreshToken, error)
+	RevokeEmployeeRefreshTokens(ctx context.Context, employeeID int) error
+	GetTokenSubject(ctx context.Context, username string) (entity.TokenSubject, error)
+	IncrementTokenVersion(ctx context.Context, username string) error
+}
+
+type tokenRepository struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewTokenRepository(db *pgxpool.Pool, logger *zap.Logger) TokenRepository {
+	return &tokenRepository{db: db, logger: logger}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, employeeID int, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.Exec(ctx, queryCreateRefreshToken, employeeID, tokenHash, expiresAt)
+	if err != nil {
+		r.logger.Error("Error creating refresh token", zap.Int("employeeID", employeeID), zap.Error(err))
+	}
+	return err
+}
+
+func (r *tokenRepository) ConsumeRefreshToken(ctx context.Context, tokenHash string) (entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := r.db.QueryRow(ctx, queryConsumeRefreshToken, tokenHash).Scan(&token.EmployeeID, &token.Username, &token.ExpiresAt, &token.RevokedAt)
+	if err != nil {
+		return entity.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *tokenRepository) GetRefreshToken(ctx context.Context, tokenHash string) (entity.RefreshToken, error) {
+	var token entity.RefreshToken
+	err := r.db.QueryRow(ctx, queryGetRefreshToken, tokenHash).Scan(&token.EmployeeID, &token.Username, &token.ExpiresAt, &token.RevokedAt)
+	if err != nil {
+		return entity.RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *tokenRepository) RevokeEmployeeRefreshTokens(ctx context.Context, employeeID int) error {
+	r.logger.Info("Revoking refresh tokens", zap.Int("employeeID", employeeID))
+	_, err := r.db.Exec(ctx, queryRevokeRefreshTokens, employeeID)
+	if err != nil {
+		r.logger.Error("Error revoking refresh tokens", zap.Error(err))
+	}
+	return err
+}
+
+func (r *tokenRepository) GetTokenSubject(ctx context.Context, username string) (entity.TokenSubject, error) {
+	var subject entity.TokenSubject
+	err := r.db.QueryRow(ctx, queryGetTokenSubject, username).Scan(&subject.Version, &subject.Role)
+	if err != nil {
+		return entity.TokenSubject{}, err
+	}
+	return subject, nil
+}
+
+func (r *tokenRepository) IncrementTokenVersion(ctx context.Context, username string) error {
+	r.logger.Info("Incrementing token version", zap.String("username", username))
+	_, err := r.db.Exec(ctx, queryIncrementTokenVersion, username)
+	if err != nil {
+		r.logger.Error("Error incrementing token version", zap.Error(err))
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/qosmioo/merch-store/pkg/jwt"
+	"github.com/qosmioo/merch-store/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidRefreshToken = newError(KindUnauthorized, "invalid refresh token")
+
+// TokenIssuer выдаёт пару access/refresh токенов после успешного входа
+type TokenIssuer interface {
+	IssueTokens(ctx context.Context, employeeID int, username string) (entity.TokenPair, error)
+}
+
+// AuthUsecase управляет жизненным циклом токенов. Он же служит jwt.Verifier
+// для AuthMiddleware: помимо подписи проверяется, что токен не отозван
+type AuthUsecase interface {
+	TokenIssuer
+	jwt.Verifier
+	Refresh(ctx context.Context, refreshToken string) (entity.TokenPair, error)
+	Logout(ctx context.Context, username, refreshToken string) error
+}
+
+type AuthConfig struct {
+	RefreshTokenTTL time.Duration
+	// QueryTimeout ограничивает время обращения к базе данных, как EmployeeConfig.QueryTimeout
+	QueryTimeout time.Duration
+}
+
+type authUsecase struct {
+	tokenRepo     repository.TokenRepository
+	tokenSigner   jwt.Signer
+	tokenVerifier jwt.Verifier
+	config        AuthConfig
+	logger        *zap.Logger
+}
+
+func NewAuthUsecase(tokenRepo repository.TokenRepository, tokenSigner jwt.Signer, tokenVerifier jwt.Verifier, config AuthConfig, logger *zap.Logger) AuthUsecase {
+	return &authUsecase{tokenRepo: tokenRepo, tokenSigner: tokenSigner, tokenVerifier: tokenVerifier, config: config, logger: logger}
+}
+
+func (u *authUsecase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if u.config.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, u.config.QueryTimeout)
+}
+
+func (u *authUsecase) IssueTokens(ctx context.Context, employeeID int, username string) (entity.TokenPair, error) {
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	subject, err := u.tokenRepo.GetTokenSubject(ctx, username)
+	if err != nil {
+		u.logger.Error("Error getting token subject", zap.Error(err))
+		return entity.TokenPair{}, err
+	}
+
+	accessToken, err := u.tokenSigner.Sign(&jwt.Claims{Username: username, Role: subject.Role, TokenVersion: subject.Version})
+	if err != nil {
+		u.logger.Error("Error generating JWT", zap.Error(err))
+		return entity.TokenPair{}, err
+	}
+
+	refreshToken, err := utils.GenerateRandomToken()
+	if err != nil {
+		u.logger.Error("Error generating refresh token", zap.Error(err))
+		return entity.TokenPair{}, err
+	}
+	expiresAt := time.Now().Add(u.config.RefreshTokenTTL)
+	if err := u.tokenRepo.CreateRefreshToken(ctx, employeeID, hashRefreshToken(refreshToken), expiresAt); err != nil {
+		return entity.TokenPair{}, err
+	}
+
+	return entity.TokenPair{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (u *authUsecase) Refresh(ctx context.Context, refreshToken string) (entity.TokenPair, error) {
+	u.logger.Info("Refresh called")
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	tokenHash := hashRefreshToken(refreshToken)
+
+	// Каждый refresh-токен одноразовый: при обновлении он отзывается и выдаётся новый
+	token, err := u.tokenRepo.ConsumeRefreshToken(ctx, tokenHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := u.handleReusedToken(ctx, tokenHash); err != nil {
+			return entity.TokenPair{}, err
+		}
+		return entity.TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		u.logger.Error("Error consuming refresh token", zap.Error(err))
+		return entity.TokenPair{}, err
+	}
+
+	u.logger.Info("Refresh token rotated", zap.String("username", token.Username))
+	return u.IssueTokens(ctx, token.EmployeeID, token.Username)
+}
+
+// handleReusedToken отзывает все сессии сотрудника, если предъявлен уже
+// использованный refresh-токен: это признак того, что токен был украден.
+// Если отозвать сессии не удалось, возвращается ошибка, и обмен токена
+// завершается ошибкой сервера, а не обычным отказом
+func (u *authUsecase) handleReusedToken(ctx context.Context, tokenHash string) error {
+	token, err := u.tokenRepo.GetRefreshToken(ctx, tokenHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		u.logger.Error("Error getting refresh token", zap.Error(err))
+		return err
+	}
+	if token.RevokedAt == nil {
+		return nil
+	}
+
+	u.logger.Warn("Revoked refresh token reused, revoking all sessions", zap.String("username", token.Username))
+	if err := u.tokenRepo.RevokeEmployeeRefreshTokens(ctx, token.EmployeeID); err != nil {
+		u.logger.Error("Error revoking refresh tokens", zap.Error(err))
+		return err
+	}
+	// Без новой версии уже выданные access-токены остались бы действительными
+	if err := u.tokenRepo.IncrementTokenVersion(ctx, token.Username); err != nil {
+		u.logger.Error("Error incrementing token version", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (u *authUsecase) Logout(ctx context.Context, username, refreshToken string) error {
+	u.logger.Info("Logout called", zap.String("username", username))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if refreshToken != "" {
+		tokenHash := hashRefreshToken(refreshToken)
+		token, err := u.tokenRepo.GetRefreshToken(ctx, tokenHash)
+		if err == nil && token.Username != username {
+			u.logger.Warn("Refresh token belongs to another employee", zap.String("username", username))
+			return ErrInvalidRefreshToken
+		}
+		if err == nil {
+			_, err = u.tokenRepo.ConsumeRefreshToken(ctx, tokenHash)
+		}
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			u.logger.Error("Error revoking refresh token", zap.Error(err))
+			return err
+		}
+	}
+
+	// Увеличение версии сразу делает недействительными выданные access-токены
+	if err := u.tokenRepo.IncrementTokenVersion(ctx, username); err != nil {
+		return err
+	}
+	u.logger.Info("Successfully logged out", zap.String("username", username))
+	return nil
+}
+
+func (u *authUsecase) Verify(ctx context.Context, tokenStr string) (*jwt.Claims, error) {
+	claims, err := u.tokenVerifier.Verify(ctx, tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	subject, err := u.tokenRepo.GetTokenSubject(ctx, claims.Username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: employee %q not found", jwt.ErrInvalidToken, claims.Username)
+	}
+	if err != nil {
+		u.logger.Error("Error getting token subject", zap.Error(err))
+		return nil, err
+	}
+	if subject.Version != claims.TokenVersion {
+		return nil, fmt.Errorf("%w: token revoked", jwt.ErrInvalidToken)
+	}
+	// Смена роли сразу лишает силы токены со старой ролью, новую выдаст refresh
+	if subject.Role != claims.Role {
+		return nil, fmt.Errorf("%w: role changed", jwt.ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+func (u *authUsecase) JWKS() jwt.JWKS {
+	return u.tokenVerifier.JWKS()
+}
+
+// hashRefreshToken хэширует refresh-токен для хранения. Токены случайные и
+// длинные, поэтому медленный хэш вроде bcrypt здесь не нужен
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/qosmioo/merch-store/pkg/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var testAuthConfig = AuthConfig{RefreshTokenTTL: time.Hour}
+
+func newTestTokenManager() *jwt.Manager {
+	manager, err := jwt.NewManager(jwt.Config{
+		TTL:          time.Hour,
+		SigningKeyID: "test",
+		Keys:         []jwt.KeyConfig{{ID: "test", Algorithm: "HS256", Secret: "test-secret-that-is-at-least-32-bytes"}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return manager
+}
+
+func TestIssueTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	var storedHash string
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").Return(entity.TokenSubject{Version: 3, Role: entity.RoleAdmin}, nil)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any(), 1, gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ int, tokenHash string, expiresAt time.Time) error {
+		storedHash = tokenHash
+		assert.WithinDuration(t, time.Now().Add(testAuthConfig.RefreshTokenTTL), expiresAt, time.Minute)
+		return nil
+	})
+
+	pair, err := usecase.IssueTokens(context.Background(), 1, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, hashRefreshToken(pair.RefreshToken), storedHash)
+
+	claims, err := tokens.Verify(context.Background(), pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, entity.RoleAdmin, claims.Role)
+	assert.Equal(t, 3, claims.TokenVersion)
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	mockRepo.EXPECT().ConsumeRefreshToken(gomock.Any(), hashRefreshToken("old")).Return(entity.RefreshToken{EmployeeID: 1, Username: "alice"}, nil)
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").Return(entity.TokenSubject{Version: 0, Role: entity.RoleEmployee}, nil)
+	mockRepo.EXPECT().CreateRefreshToken(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil)
+
+	pair, err := usecase.Refresh(context.Background(), "old")
+	require.NoError(t, err)
+	assert.NotEqual(t, "old", pair.RefreshToken)
+	assert.NotEmpty(t, pair.AccessToken)
+}
+
+func TestRefresh_UnknownToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	mockRepo.EXPECT().ConsumeRefreshToken(gomock.Any(), hashRefreshToken("unknown")).Return(entity.RefreshToken{}, pgx.ErrNoRows)
+	mockRepo.EXPECT().GetRefreshToken(gomock.Any(), hashRefreshToken("unknown")).Return(entity.RefreshToken{}, pgx.ErrNoRows)
+
+	_, err := usecase.Refresh(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_ReusedTokenRevokesAllSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	revokedAt := time.Now().Add(-time.Minute)
+	mockRepo.EXPECT().ConsumeRefreshToken(gomock.Any(), hashRefreshToken("stolen")).Return(entity.RefreshToken{}, pgx.ErrNoRows)
+	mockRepo.EXPECT().GetRefreshToken(gomock.Any(), hashRefreshToken("stolen")).Return(entity.RefreshToken{EmployeeID: 1, Username: "alice", RevokedAt: &revokedAt}, nil)
+	mockRepo.EXPECT().RevokeEmployeeRefreshTokens(gomock.Any(), 1).Return(nil)
+	mockRepo.EXPECT().IncrementTokenVersion(gomock.Any(), "alice").Return(nil)
+
+	_, err := usecase.Refresh(context.Background(), "stolen")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_ReusedTokenFailsWhenSessionsAreNotRevoked(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	revokedAt := time.Now().Add(-time.Minute)
+	dbErr := errors.New("db error")
+	mockRepo.EXPECT().ConsumeRefreshToken(gomock.Any(), hashRefreshToken("stolen")).Return(entity.RefreshToken{}, pgx.ErrNoRows)
+	mockRepo.EXPECT().GetRefreshToken(gomock.Any(), hashRefreshToken("stolen")).Return(entity.RefreshToken{EmployeeID: 1, Username: "alice", RevokedAt: &revokedAt}, nil)
+	mockRepo.EXPECT().RevokeEmployeeRefreshTokens(gomock.Any(), 1).Return(nil)
+	mockRepo.EXPECT().IncrementTokenVersion(gomock.Any(), "alice").Return(dbErr)
+
+	_, err := usecase.Refresh(context.Background(), "stolen")
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestVerify_RevokedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	accessToken, err := tokens.Sign(&jwt.Claims{Username: "alice", Role: entity.RoleEmployee, TokenVersion: 1})
+	require.NoError(t, err)
+
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").Return(entity.TokenSubject{Version: 1, Role: entity.RoleEmployee}, nil)
+	_, err = usecase.Verify(context.Background(), accessToken)
+	assert.NoError(t, err)
+
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").Return(entity.TokenSubject{Version: 2, Role: entity.RoleEmployee}, nil)
+	_, err = usecase.Verify(context.Background(), accessToken)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+}
+
+func TestVerify_UsesRequestContextWithTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	config := testAuthConfig
+	config.QueryTimeout = time.Minute
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, config, zap.NewNop())
+
+	accessToken, err := tokens.Sign(&jwt.Claims{Username: "alice", Role: entity.RoleEmployee})
+	require.NoError(t, err)
+
+	// Запрос к базе выполняется в контексте HTTP-запроса с ограничением QueryTimeout
+	type requestKey struct{}
+	ctx := context.WithValue(context.Background(), requestKey{}, "request")
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").DoAndReturn(func(ctx context.Context, username string) (entity.TokenSubject, error) {
+		assert.Equal(t, "request", ctx.Value(requestKey{}))
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		return entity.TokenSubject{Role: entity.RoleEmployee}, nil
+	})
+	_, err = usecase.Verify(ctx, accessToken)
+	assert.NoError(t, err)
+}
+
+func TestVerify_RoleChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	accessToken, err := tokens.Sign(&jwt.Claims{Username: "alice", Role: entity.RoleAdmin})
+	require.NoError(t, err)
+
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").Return(entity.TokenSubject{Role: entity.RoleEmployee}, nil)
+	_, err = usecase.Verify(context.Background(), accessToken)
+	assert.ErrorIs(t, err, jwt.ErrInvalidToken)
+}
+
+func TestVerify_StorageErrorIsNotInvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	accessToken, err := tokens.Sign(&jwt.Claims{Username: "alice", Role: entity.RoleEmployee})
+	require.NoError(t, err)
+
+	dbErr := errors.New("db error")
+	mockRepo.EXPECT().GetTokenSubject(gomock.Any(), "alice").Return(entity.TokenSubject{}, dbErr)
+	_, err = usecase.Verify(context.Background(), accessToken)
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, jwt.ErrInvalidToken)
+}
+
+func TestLogout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	tokenHash := hashRefreshToken("refresh")
+	mockRepo.EXPECT().GetRefreshToken(gomock.Any(), tokenHash).Return(entity.RefreshToken{EmployeeID: 1, Username: "alice"}, nil)
+	mockRepo.EXPECT().ConsumeRefreshToken(gomock.Any(), tokenHash).Return(entity.RefreshToken{EmployeeID: 1, Username: "alice"}, nil)
+	mockRepo.EXPECT().IncrementTokenVersion(gomock.Any(), "alice").Return(nil)
+
+	assert.NoError(t, usecase.Logout(context.Background(), "alice", "refresh"))
+}
+
+func TestLogout_ForeignRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockTokenRepository(ctrl)
+	tokens := newTestTokenManager()
+	usecase := NewAuthUsecase(mockRepo, tokens, tokens, testAuthConfig, zap.NewNop())
+
+	mockRepo.EXPECT().GetRefreshToken(gomock.Any(), hashRefreshToken("refresh")).Return(entity.RefreshToken{EmployeeID: 2, Username: "bob"}, nil)
+
+	assert.ErrorIs(t, usecase.Logout(context.Background(), "alice", "refresh"), ErrInvalidRefreshToken)
+}
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrEmptyCart = newError(KindInvalid, "cart is empty")
+	ErrNotInCart = newError(KindNotFound, "item is not in the cart")
+)
+
+// CartUsecase управляет корзиной сотрудника и оформляет её одной покупкой
+type CartUsecase interface {
+	GetCart(ctx context.Context, employeeID int) (entity.Cart, error)
+	// AddToCart добавляет quantity единиц товара. В одной позиции может быть
+	// не больше MaxPurchaseQuantity единиц
+	AddToCart(ctx context.Context, employeeID int, itemName string, quantity int) (entity.Cart, error)
+	RemoveFromCart(ctx context.Context, employeeID int, itemName string) (entity.Cart, error)
+	// Checkout покупает всё содержимое корзины по текущим ценам и очищает её.
+	// Если хотя бы одну позицию купить нельзя, не покупается ничего
+	Checkout(ctx context.Context, employeeID int) (entity.Cart, error)
+}
+
+type CartConfig struct {
+	// QueryTimeout ограничивает время обращения к базе данных, как EmployeeConfig.QueryTimeout
+	QueryTimeout time.Duration
+}
+
+type cartUsecase struct {
+	cartRepo     repository.CartRepository
+	employeeRepo repository.EmployeeRepository
+	config       CartConfig
+	logger       *zap.Logger
+}
+
+func NewCartUsecase(cartRepo repository.CartRepository, employeeRepo repository.EmployeeRepository, config CartConfig, logger *zap.Logger) CartUsecase {
+	return &cartUsecase{cartRepo: cartRepo, employeeRepo: employeeRepo, config: config, logger: logger}
+}
+
+func (u *cartUsecase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if u.config.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, u.config.QueryTimeout)
+}
+
+func (u *cartUsecase) GetCart(ctx context.Context, employeeID int) (entity.Cart, error) {
+	u.logger.Info("GetCart called", zap.Int("employeeID", employeeID))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	return u.getCart(ctx, employeeID)
+}
+
+func (u *cartUsecase) getCart(ctx context.Context, employeeID int) (entity.Cart, error) {
+	items, err := u.cartRepo.ListCart(ctx, employeeID)
+	if err != nil {
+		u.logger.Error("Error listing cart", zap.Error(err))
+		return entity.Cart{}, err
+	}
+	cart := entity.Cart{Items: items}
+	for _, item := range items {
+		cart.Total += item.Price * item.Quantity
+	}
+	return cart, nil
+}
+
+func (u *cartUsecase) AddToCart(ctx context.Context, employeeID int, itemName string, quantity int) (entity.Cart, error) {
+	u.logger.Info("AddToCart called", zap.Int("employeeID", employeeID), zap.String("itemName", itemName), zap.Int("quantity", quantity))
+	if quantity <= 0 || quantity > MaxPurchaseQuantity {
+		return entity.Cart{}, ErrInvalidQuantity
+	}
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	if _, err := u.employeeRepo.GetMerchPrice(ctx, itemName); errors.Is(err, pgx.ErrNoRows) {
+		return entity.Cart{}, ErrItemNotFound
+	} else if err != nil {
+		return entity.Cart{}, err
+	}
+
+	err := u.cartRepo.AddCartItem(ctx, employeeID, itemName, quantity, MaxPurchaseQuantity)
+	if errors.Is(err, repository.ErrCartQuantityExceeded) {
+		return entity.Cart{}, ErrInvalidQuantity
+	}
+	if err != nil {
+		return entity.Cart{}, err
+	}
+	return u.getCart(ctx, employeeID)
+}
+
+func (u *cartUsecase) RemoveFromCart(ctx context.Context, employeeID int, itemName string) (entity.Cart, error) {
+	u.logger.Info("RemoveFromCart called", zap.Int("employeeID", employeeID), zap.String("itemName", itemName))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	err := u.cartRepo.RemoveCartItem(ctx, employeeID, itemName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Cart{}, ErrNotInCart
+	}
+	if err != nil {
+		return entity.Cart{}, err
+	}
+	return u.getCart(ctx, employeeID)
+}
+
+func (u *cartUsecase) Checkout(ctx context.Context, employeeID int) (entity.Cart, error) {
+	u.logger.Info("Checkout called", zap.Int("employeeID", employeeID))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+
+	var order entity.Cart
+	err := withTx(ctx, u.employeeRepo.BeginTransaction, u.logger, func(tx pgx.Tx) error {
+		lines, err := u.cartRepo.ListCartForUpdateTx(ctx, tx, employeeID)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return ErrEmptyCart
+		}
+
+		if order, err = purchaseTx(ctx, u.employeeRepo, tx, employeeID, lines, u.logger); err != nil {
+			return err
+		}
+		return u.cartRepo.ClearCartTx(ctx, tx, employeeID)
+	})
+	if err != nil {
+		return entity.Cart{}, err
+	}
+
+	u.logger.Info("Successfully checked out cart", zap.Int("employeeID", employeeID), zap.Int("items", len(order.Items)), zap.Int("total", order.Total))
+	return order, nil
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheckout_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	employeeID := 1
+	lines := []entity.CartItem{{Item: "cup", Quantity: 2}, {Item: "hoody", Quantity: 1}, {Item: "socks", Quantity: 3}}
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockCart.EXPECT().ListCartForUpdateTx(ctx, mockTx, employeeID).Return(lines, nil)
+	gomock.InOrder(
+		mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "cup").Return(20, nil),
+		mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "cup", 2).Return(nil),
+		mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "hoody").Return(300, nil),
+		mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "hoody", 1).Return(nil),
+		mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "socks").Return(10, nil),
+		mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "socks", 3).Return(nil),
+		mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, employeeID).Return(entity.Employee{ID: employeeID, Coins: 1000}, nil),
+	)
+	// Вся корзина списывается одной проводкой
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, entity.JournalEntry{
+		Kind:        entity.EntryPurchase,
+		Description: "cup, hoody, socks",
+		Postings: []entity.Posting{
+			{Account: entity.EmployeeAccount(employeeID), Amount: -370},
+			{Account: entity.StoreAccount, Amount: 370},
+		},
+	}).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, employeeID, "cup", 2).Return(nil)
+	mockRepo.EXPECT().RecordPurchaseTx(ctx, mockTx, employeeID, "cup", 20, 2).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, employeeID, "hoody", 1).Return(nil)
+	mockRepo.EXPECT().RecordPurchaseTx(ctx, mockTx, employeeID, "hoody", 300, 1).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, employeeID, "socks", 3).Return(nil)
+	mockRepo.EXPECT().RecordPurchaseTx(ctx, mockTx, employeeID, "socks", 10, 3).Return(nil)
+	mockCart.EXPECT().ClearCartTx(ctx, mockTx, employeeID).Return(nil)
+	mockTx.EXPECT().Commit(ctx).Return(nil)
+
+	order, err := usecase.Checkout(ctx, employeeID)
+	require.NoError(t, err)
+	assert.Equal(t, 370, order.Total)
+	assert.Equal(t, []entity.CartItem{{Item: "cup", Quantity: 2, Price: 20}, {Item: "hoody", Quantity: 1, Price: 300}, {Item: "socks", Quantity: 3, Price: 10}}, order.Items)
+}
+
+func TestCheckout_InsufficientFundsRollsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	// Каждая позиция по отдельности по карману, вместе — нет
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockCart.EXPECT().ListCartForUpdateTx(ctx, mockTx, 1).Return([]entity.CartItem{{Item: "cup", Quantity: 1}, {Item: "hoody", Quantity: 1}}, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "cup").Return(20, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "cup", 1).Return(nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "hoody").Return(300, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "hoody", 1).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, 1).Return(entity.Employee{ID: 1, Coins: 310}, nil)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	_, err := usecase.Checkout(ctx, 1)
+	assert.ErrorIs(t, err, ErrInsufficientCoins)
+}
+
+func TestCheckout_EmptyCart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockCart.EXPECT().ListCartForUpdateTx(ctx, mockTx, 1).Return([]entity.CartItem{}, nil)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	_, err := usecase.Checkout(ctx, 1)
+	assert.ErrorIs(t, err, ErrEmptyCart)
+}
+
+func TestCheckout_CommitFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+	commitErr := errors.New("connection reset")
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockCart.EXPECT().ListCartForUpdateTx(ctx, mockTx, 1).Return([]entity.CartItem{{Item: "cup", Quantity: 1}}, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "cup").Return(20, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "cup", 1).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, 1).Return(entity.Employee{ID: 1, Coins: 100}, nil)
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, gomock.Any()).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, 1, "cup", 1).Return(nil)
+	mockRepo.EXPECT().RecordPurchaseTx(ctx, mockTx, 1, "cup", 20, 1).Return(nil)
+	mockCart.EXPECT().ClearCartTx(ctx, mockTx, 1).Return(nil)
+	mockTx.EXPECT().Commit(ctx).Return(commitErr)
+
+	order, err := usecase.Checkout(ctx, 1)
+	assert.ErrorIs(t, err, commitErr)
+	assert.Empty(t, order.Items)
+}
+
+func TestAddToCart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	mockRepo.EXPECT().GetMerchPrice(ctx, "cup").Return(20, nil)
+	mockCart.EXPECT().AddCartItem(ctx, 1, "cup", 2, MaxPurchaseQuantity).Return(nil)
+	mockCart.EXPECT().ListCart(ctx, 1).Return([]entity.CartItem{{Item: "cup", Quantity: 2, Price: 20}, {Item: "pen", Quantity: 1, Price: 10}}, nil)
+
+	cart, err := usecase.AddToCart(ctx, 1, "cup", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 50, cart.Total)
+	assert.Len(t, cart.Items, 2)
+}
+
+func TestAddToCart_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	_, err := usecase.AddToCart(ctx, 1, "cup", 0)
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	mockRepo.EXPECT().GetMerchPrice(ctx, "unknown").Return(0, pgx.ErrNoRows)
+	_, err = usecase.AddToCart(ctx, 1, "unknown", 1)
+	assert.ErrorIs(t, err, ErrItemNotFound)
+
+	mockRepo.EXPECT().GetMerchPrice(ctx, "cup").Return(20, nil)
+	mockCart.EXPECT().AddCartItem(ctx, 1, "cup", 60, MaxPurchaseQuantity).Return(repository.ErrCartQuantityExceeded)
+	_, err = usecase.AddToCart(ctx, 1, "cup", 60)
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+}
+
+func TestRemoveFromCart_NotInCart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCart := repository.NewMockCartRepository(ctrl)
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	usecase := NewCartUsecase(mockCart, mockRepo, CartConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	mockCart.EXPECT().RemoveCartItem(ctx, 1, "cup").Return(pgx.ErrNoRows)
+
+	_, err := usecase.RemoveFromCart(ctx, 1, "cup")
+	assert.ErrorIs(t, err, ErrNotInCart)
+}
@@ -2,110 +2,282 @@ package usecase
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/qosmioo/merch-store/internal/entity"
 	"github.com/qosmioo/merch-store/internal/repository"
-	"github.com/qosmioo/merch-store/pkg/jwt"
+	"github.com/qosmioo/merch-store/pkg/hasher"
 	"go.uber.org/zap"
 )
 
 type EmployeeUsecase interface {
-	GetEmployeeInfo(employeeID int) (entity.InfoResponse, error)
-	TransferCoins(fromEmployeeID, toEmployeeID, amount int) error
-	BuyMerch(employeeID int, itemName string) error
-	Authenticate(username, password string) (string, error)
-	GetEmployeeIDByUsername(username string) (int, error)
+	GetEmployeeInfo(ctx context.Context, employeeID int) (entity.InfoResponse, error)
+	// GetCoinHistory возвращает страницу истории переводов. cursor — значение
+	// NextCursor предыдущей страницы, пустой cursor означает первую страницу
+	GetCoinHistory(ctx context.Context, employeeID int, filter entity.HistoryFilter, cursor string, limit int) (entity.HistoryPage, error)
+	// TransferCoins переводит монеты. Сообщение к переводу очищается от
+	// управляющих символов и не должно превышать 200 символов
+	TransferCoins(ctx context.Context, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error
+	// ListKudos возвращает последние переводы, опубликованные отправителями на стене благодарностей
+	ListKudos(ctx context.Context, limit int) ([]entity.Kudos, error)
+	// BuyMerch покупает quantity единиц товара по текущей цене: монеты
+	// списываются, а товар выдаётся в одной транзакции
+	BuyMerch(ctx context.Context, employeeID int, itemName string, quantity int) error
+	Authenticate(ctx context.Context, username, password string) (entity.TokenPair, error)
+	Register(ctx context.Context, username, password string) (entity.TokenPair, error)
+	GetEmployeeIDByUsername(ctx context.Context, username string) (int, error)
+	SetEmployeeRole(ctx context.Context, username, role string) error
+}
+
+// DefaultStartingCoins — баланс нового сотрудника
+const DefaultStartingCoins = 1000
+
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+	maxMessageLength    = 200
+	// MaxPurchaseQuantity ограничивает количество единиц товара в одной покупке
+	MaxPurchaseQuantity = 100
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt учитывает только первые 72 байта пароля
+	maxPasswordLength = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,32}$`)
+
+var (
+	ErrInvalidUsername    = newError(KindInvalid, "username must be 3-32 characters long and contain only letters, digits, '.', '_' or '-'")
+	ErrInvalidPassword    = newError(KindInvalid, "password must be 8-72 characters long")
+	ErrUsernameTaken      = newError(KindConflict, "username already taken")
+	ErrInvalidCredentials = newError(KindUnauthorized, "invalid credentials")
+	ErrInvalidRole        = newError(KindInvalid, "role must be one of: employee, admin, auditor")
+	ErrEmployeeNotFound   = newError(KindNotFound, "employee not found")
+	ErrRecipientNotFound  = newError(KindInvalid, "recipient not found")
+	ErrSelfTransfer       = newError(KindInvalid, "cannot transfer coins to yourself")
+	ErrInvalidAmount      = newError(KindInvalid, "amount must be a positive integer")
+	ErrInsufficientCoins  = newError(KindInvalid, "insufficient coins")
+	ErrItemNotFound       = newError(KindNotFound, "item not found")
+	ErrOutOfStock         = newError(KindConflict, "out of stock")
+	ErrInvalidQuantity    = newError(KindInvalid, "quantity must be between 1 and 100")
+	ErrInvalidHistory     = newError(KindInvalid, "invalid history filter: direction must be sent or received, from must be before to, limit must be between 1 and 100")
+	ErrInvalidCursor      = newError(KindInvalid, "invalid cursor")
+	ErrInvalidMessage     = newError(KindInvalid, "message must be at most 200 characters long")
+	ErrInvalidKudosLimit  = newError(KindInvalid, "limit must be between 1 and 100")
+)
+
+// EmployeeConfig задаёт настраиваемое поведение сценариев работы с сотрудниками
+type EmployeeConfig struct {
+	// AutoProvision включает создание сотрудника при первом входе с неизвестным логином
+	AutoProvision bool
+	StartingCoins int
+	// QueryTimeout ограничивает время обращения к базе данных в рамках одного
+	// вызова сценария. Ноль означает, что действуют только ограничения контекста запроса
+	QueryTimeout time.Duration
 }
 
 type employeeUsecase struct {
-	employeeRepo repository.EmployeeRepository
-	logger       *zap.Logger
+	employeeRepo   repository.EmployeeRepository
+	passwordHasher hasher.Hasher
+	tokenIssuer    TokenIssuer
+	config         EmployeeConfig
+	logger         *zap.Logger
+}
+
+func NewEmployeeUsecase(employeeRepo repository.EmployeeRepository, passwordHasher hasher.Hasher, tokenIssuer TokenIssuer, config EmployeeConfig, logger *zap.Logger) EmployeeUsecase {
+	return &employeeUsecase{employeeRepo: employeeRepo, passwordHasher: passwordHasher, tokenIssuer: tokenIssuer, config: config, logger: logger}
 }
 
-func NewEmployeeUsecase(employeeRepo repository.EmployeeRepository, logger *zap.Logger) EmployeeUsecase {
-	return &employeeUsecase{employeeRepo: employeeRepo, logger: logger}
+// withTimeout применяет QueryTimeout к контексту запроса
+func (u *employeeUsecase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if u.config.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, u.config.QueryTimeout)
 }
 
-func (u *employeeUsecase) GetEmployeeInfo(employeeID int) (entity.InfoResponse, error) {
+func (u *employeeUsecase) GetEmployeeInfo(ctx context.Context, employeeID int) (entity.InfoResponse, error) {
 	u.logger.Info("GetEmployeeInfo called", zap.Int("employeeID", employeeID))
-	employee, err := u.employeeRepo.GetEmployeeByID(employeeID)
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	employee, err := u.employeeRepo.GetEmployeeByID(ctx, employeeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.InfoResponse{}, ErrEmployeeNotFound
+	}
 	if err != nil {
 		u.logger.Error("Error getting employee by ID", zap.Error(err))
 		return entity.InfoResponse{}, err
 	}
 
-	inventory, err := u.employeeRepo.GetEmployeeInventory(employeeID)
+	inventory, err := u.employeeRepo.GetEmployeeInventory(ctx, employeeID)
 	if err != nil {
 		u.logger.Error("Error getting employee inventory", zap.Error(err))
 		return entity.InfoResponse{}, err
 	}
 
-	coinHistory, err := u.employeeRepo.GetEmployeeCoinHistory(employeeID)
+	coinHistory, err := u.employeeRepo.GetEmployeeCoinHistory(ctx, employeeID)
 	if err != nil {
 		u.logger.Error("Error getting employee coin history", zap.Error(err))
 		return entity.InfoResponse{}, err
 	}
 
+	purchases, err := u.employeeRepo.GetEmployeePurchases(ctx, employeeID)
+	if err != nil {
+		u.logger.Error("Error getting employee purchases", zap.Error(err))
+		return entity.InfoResponse{}, err
+	}
+
 	u.logger.Info("Successfully retrieved employee info", zap.Int("employeeID", employeeID))
 	return entity.InfoResponse{
 		Coins:       employee.Coins,
 		Inventory:   inventory,
 		CoinHistory: coinHistory,
+		Purchases:   purchases,
 	}, nil
 }
 
-func (u *employeeUsecase) TransferCoins(fromEmployeeID, toEmployeeID, amount int) (err error) {
-	u.logger.Info("TransferCoins called", zap.Int("fromEmployeeID", fromEmployeeID), zap.Int("toEmployeeID", toEmployeeID), zap.Int("amount", amount))
-
-	var tx pgx.Tx
-	if tx, err = u.employeeRepo.BeginTransaction(); err != nil {
-		u.logger.Error("Error starting transaction", zap.Error(err))
-		return err
+func (u *employeeUsecase) GetCoinHistory(ctx context.Context, employeeID int, filter entity.HistoryFilter, cursor string, limit int) (entity.HistoryPage, error) {
+	u.logger.Info("GetCoinHistory called", zap.Int("employeeID", employeeID), zap.Any("filter", filter), zap.String("cursor", cursor), zap.Int("limit", limit))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if limit == 0 {
+		limit = DefaultHistoryLimit
+	}
+	validDirection := filter.Direction == "" || filter.Direction == entity.DirectionSent || filter.Direction == entity.DirectionReceived
+	validPeriod := filter.From.IsZero() || filter.To.IsZero() || filter.From.Before(filter.To)
+	if !validDirection || !validPeriod || limit < 0 || limit > MaxHistoryLimit {
+		return entity.HistoryPage{}, ErrInvalidHistory
 	}
-	defer func(err *error) {
-		if *err != nil {
-			tx.Rollback(context.Background())
-		} else {
-			tx.Commit(context.Background())
+
+	var after *entity.HistoryCursor
+	if cursor != "" {
+		decoded, err := decodeHistoryCursor(cursor)
+		if err != nil {
+			u.logger.Warn("Invalid history cursor", zap.String("cursor", cursor), zap.Error(err))
+			return entity.HistoryPage{}, ErrInvalidCursor
 		}
-	}(&err)
+		after = &decoded
+	}
 
-	fromEmployee, err := u.employeeRepo.GetEmployeeByIDTx(tx, fromEmployeeID)
+	// Лишняя запись показывает, есть ли следующая страница
+	entries, err := u.employeeRepo.GetEmployeeHistory(ctx, employeeID, filter, after, limit+1)
 	if err != nil {
-		u.logger.Error("Error getting from employee by ID", zap.Error(err))
-		return err
+		u.logger.Error("Error getting employee history", zap.Error(err))
+		return entity.HistoryPage{}, err
 	}
 
-	if fromEmployee.Coins < amount {
-		u.logger.Warn("Insufficient coins", zap.Int("fromEmployeeID", fromEmployeeID), zap.Int("amount", amount))
-		err = errors.New("insufficient coins")
-		return err
+	page := entity.HistoryPage{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeHistoryCursor(entity.HistoryCursor{CreatedAt: last.CreatedAt, ID: last.ID})
 	}
+	return page, nil
+}
+
+// Курсор непрозрачен для клиента: это время и id последней записи страницы
+func encodeHistoryCursor(cursor entity.HistoryCursor) string {
+	raw := strconv.FormatInt(cursor.CreatedAt.UnixNano(), 10) + ":" + strconv.Itoa(cursor.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
 
-	toEmployee, err := u.employeeRepo.GetEmployeeByIDTx(tx, toEmployeeID)
+func decodeHistoryCursor(cursor string) (entity.HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
 	if err != nil {
-		u.logger.Error("Error getting to employee by ID", zap.Error(err))
-		return err
+		return entity.HistoryCursor{}, err
 	}
-
-	// Обновление количества монет
-	err = u.employeeRepo.UpdateEmployeeCoinsTx(tx, fromEmployeeID, fromEmployee.Coins-amount)
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return entity.HistoryCursor{}, errors.New("malformed cursor")
+	}
+	createdAt, err := strconv.ParseInt(nanos, 10, 64)
 	if err != nil {
-		u.logger.Error("Error updating from employee coins", zap.Error(err))
-		return err
+		return entity.HistoryCursor{}, err
 	}
-
-	err = u.employeeRepo.UpdateEmployeeCoinsTx(tx, toEmployeeID, toEmployee.Coins+amount)
+	entryID, err := strconv.Atoi(id)
 	if err != nil {
-		u.logger.Error("Error updating to employee coins", zap.Error(err))
-		return err
+		return entity.HistoryCursor{}, err
 	}
+	return entity.HistoryCursor{CreatedAt: time.Unix(0, createdAt).UTC(), ID: entryID}, nil
+}
 
-	err = u.employeeRepo.RecordTransactionTx(tx, fromEmployeeID, toEmployeeID, amount)
+func (u *employeeUsecase) TransferCoins(ctx context.Context, fromEmployeeID, toEmployeeID, amount int, memo entity.Memo) error {
+	u.logger.Info("TransferCoins called", zap.Int("fromEmployeeID", fromEmployeeID), zap.Int("toEmployeeID", toEmployeeID), zap.Int("amount", amount), zap.Bool("public", memo.Public))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if fromEmployeeID == toEmployeeID {
+		return ErrSelfTransfer
+	}
+	// Отрицательная сумма позволила бы забрать монеты у получателя
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	memo.Message = sanitizeMessage(memo.Message)
+	if utf8.RuneCountInString(memo.Message) > maxMessageLength {
+		return ErrInvalidMessage
+	}
+
+	err := withTx(ctx, u.employeeRepo.BeginTransaction, u.logger, func(tx pgx.Tx) error {
+		// Строки отправителя и получателя блокируются одним запросом в порядке
+		// возрастания ID: встречные переводы A→B и B→A ждут друг друга, а не
+		// захватывают по одной строке каждый, что привело бы к взаимной блокировке
+		employees, err := u.employeeRepo.LockEmployeesTx(ctx, tx, fromEmployeeID, toEmployeeID)
+		if err != nil {
+			u.logger.Error("Error locking employees", zap.Error(err))
+			return err
+		}
+		var fromEmployee *entity.Employee
+		toFound := false
+		for i := range employees {
+			switch employees[i].ID {
+			case fromEmployeeID:
+				fromEmployee = &employees[i]
+			case toEmployeeID:
+				toFound = true
+			}
+		}
+		if fromEmployee == nil {
+			return ErrEmployeeNotFound
+		}
+		if !toFound {
+			return ErrRecipientNotFound
+		}
+
+		if fromEmployee.Coins < amount {
+			u.logger.Warn("Insufficient coins", zap.Int("fromEmployeeID", fromEmployeeID), zap.Int("amount", amount))
+			return ErrInsufficientCoins
+		}
+
+		err = u.employeeRepo.PostJournalEntryTx(ctx, tx, entity.JournalEntry{
+			Kind: entity.EntryTransfer,
+			Postings: []entity.Posting{
+				{Account: entity.EmployeeAccount(fromEmployeeID), Amount: -amount},
+				{Account: entity.EmployeeAccount(toEmployeeID), Amount: amount},
+			},
+		})
+		if err != nil {
+			u.logger.Error("Error posting transfer to ledger", zap.Error(err))
+			return err
+		}
+
+		err = u.employeeRepo.RecordTransactionTx(ctx, tx, fromEmployeeID, toEmployeeID, amount, memo)
+		if err != nil {
+			u.logger.Error("Error recording transaction", zap.Error(err))
+			return err
+		}
+		return nil
+	})
 	if err != nil {
-		u.logger.Error("Error recording transaction", zap.Error(err))
 		return err
 	}
 
@@ -113,83 +285,244 @@ func (u *employeeUsecase) TransferCoins(fromEmployeeID, toEmployeeID, amount int
 	return nil
 }
 
-func (u *employeeUsecase) BuyMerch(employeeID int, itemName string) error {
-	u.logger.Info("BuyMerch called", zap.Int("employeeID", employeeID), zap.String("itemName", itemName))
-	price, err := u.employeeRepo.GetMerchPrice(itemName)
-	if err != nil {
-		u.logger.Error("Error getting merch price", zap.Error(err))
-		return err
+// sanitizeMessage удаляет управляющие и невидимые символы форматирования (в том
+// числе смену направления текста), заменяет переводы строк и другие пробельные
+// символы одним пробелом и обрезает пробелы по краям. HTML не экранируется:
+// сообщение отдаётся в JSON, а экранирование при выводе остаётся за клиентом
+func sanitizeMessage(message string) string {
+	var builder strings.Builder
+	pendingSpace := false
+	for _, r := range message {
+		switch {
+		case unicode.IsSpace(r):
+			pendingSpace = true
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r), r == utf8.RuneError:
+			continue
+		}
+		if pendingSpace && builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		pendingSpace = false
+		builder.WriteRune(r)
 	}
+	return builder.String()
+}
 
-	employee, err := u.employeeRepo.GetEmployeeByID(employeeID)
-	if err != nil {
-		u.logger.Error("Error getting employee by ID", zap.Error(err))
-		return err
+func (u *employeeUsecase) ListKudos(ctx context.Context, limit int) ([]entity.Kudos, error) {
+	u.logger.Info("ListKudos called", zap.Int("limit", limit))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if limit == 0 {
+		limit = DefaultHistoryLimit
 	}
-
-	if employee.Coins < price {
-		u.logger.Warn("Insufficient coins for purchase", zap.Int("employeeID", employeeID), zap.Int("price", price))
-		return errors.New("insufficient coins")
+	if limit < 0 || limit > MaxHistoryLimit {
+		return nil, ErrInvalidKudosLimit
 	}
+	return u.employeeRepo.ListKudos(ctx, limit)
+}
 
-	err = u.employeeRepo.UpdateEmployeeCoins(employeeID, employee.Coins-price)
-	if err != nil {
-		u.logger.Error("Error updating employee coins after purchase", zap.Error(err))
-		return err
+func (u *employeeUsecase) BuyMerch(ctx context.Context, employeeID int, itemName string, quantity int) error {
+	u.logger.Info("BuyMerch called", zap.Int("employeeID", employeeID), zap.String("itemName", itemName), zap.Int("quantity", quantity))
+	if quantity <= 0 || quantity > MaxPurchaseQuantity {
+		return ErrInvalidQuantity
 	}
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
 
-	err = u.employeeRepo.AddToInventory(employeeID, itemName)
+	err := withTx(ctx, u.employeeRepo.BeginTransaction, u.logger, func(tx pgx.Tx) error {
+		_, err := purchaseTx(ctx, u.employeeRepo, tx, employeeID, []entity.CartItem{{Item: itemName, Quantity: quantity}}, u.logger)
+		return err
+	})
 	if err != nil {
-		u.logger.Error("Error adding item to inventory", zap.Error(err))
 		return err
 	}
 
-	u.logger.Info("Successfully purchased merch", zap.Int("employeeID", employeeID), zap.String("itemName", itemName))
+	u.logger.Info("Successfully purchased merch", zap.Int("employeeID", employeeID), zap.String("itemName", itemName), zap.Int("quantity", quantity))
 	return nil
 }
 
-func (u *employeeUsecase) Authenticate(username, password string) (string, error) {
-	u.logger.Info("Authenticate called", zap.String("username", username))
-	employee, err := u.employeeRepo.GetEmployeeByUsername(username)
-	if err != nil {
-		u.logger.Warn("User not found, creating new user", zap.String("username", username))
-		newEmployee := entity.Employee{
-			Name:     username,
-			Password: password,
-			Coins:    1000,
+// purchaseTx покупает позиции lines в транзакции tx: оценивает каждую по
+// текущей цене, списывает остатки, проверяет баланс и проводит всю сумму
+// одной проводкой. Возвращает позиции с ценами и итоговую сумму
+func purchaseTx(ctx context.Context, repo repository.EmployeeRepository, tx pgx.Tx, employeeID int, lines []entity.CartItem, logger *zap.Logger) (entity.Cart, error) {
+	cart := entity.Cart{Items: make([]entity.CartItem, 0, len(lines))}
+	items := make([]string, 0, len(lines))
+	for _, line := range lines {
+		price, err := repo.GetMerchPriceTx(ctx, tx, line.Item)
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.Warn("Merch not found", zap.String("itemName", line.Item))
+			return entity.Cart{}, ErrItemNotFound
 		}
-		err = u.employeeRepo.CreateEmployee(newEmployee)
 		if err != nil {
-			u.logger.Error("Failed to create new user", zap.Error(err))
-			return "", errors.New("failed to create new user")
+			logger.Error("Error getting merch price", zap.Error(err))
+			return entity.Cart{}, err
+		}
+
+		// Остаток уменьшается условным UPDATE, поэтому два покупателя не могут
+		// забрать последнюю единицу товара одновременно
+		err = repo.DecrementMerchStockTx(ctx, tx, line.Item, line.Quantity)
+		if errors.Is(err, repository.ErrOutOfStock) {
+			logger.Warn("Merch out of stock", zap.String("itemName", line.Item))
+			return entity.Cart{}, ErrOutOfStock
 		}
-		token, err := jwt.GenerateJWT(username)
 		if err != nil {
-			u.logger.Error("Error generating JWT", zap.Error(err))
-			return "", err
+			logger.Error("Error decrementing merch stock", zap.Error(err))
+			return entity.Cart{}, err
 		}
-		u.logger.Info("Successfully authenticated new user", zap.String("username", username))
-		return token, nil
+
+		line.Price = price
+		cart.Items = append(cart.Items, line)
+		cart.Total += price * line.Quantity
+		items = append(items, line.Item)
+	}
+
+	// Строка сотрудника блокируется до конца транзакции, поэтому параллельные
+	// покупки не могут списать монеты с одного и того же баланса дважды
+	employee, err := repo.GetEmployeeByIDTx(ctx, tx, employeeID)
+	if err != nil {
+		logger.Error("Error getting employee by ID", zap.Error(err))
+		return entity.Cart{}, err
 	}
 
-	if employee.Password != password {
+	if employee.Coins < cart.Total {
+		logger.Warn("Insufficient coins for purchase", zap.Int("employeeID", employeeID), zap.Int("total", cart.Total))
+		return entity.Cart{}, ErrInsufficientCoins
+	}
+
+	err = repo.PostJournalEntryTx(ctx, tx, entity.JournalEntry{
+		Kind:        entity.EntryPurchase,
+		Description: strings.Join(items, ", "),
+		Postings: []entity.Posting{
+			{Account: entity.EmployeeAccount(employeeID), Amount: -cart.Total},
+			{Account: entity.StoreAccount, Amount: cart.Total},
+		},
+	})
+	if err != nil {
+		logger.Error("Error posting purchase to ledger", zap.Error(err))
+		return entity.Cart{}, err
+	}
+
+	for _, line := range cart.Items {
+		if err := repo.AddToInventoryTx(ctx, tx, employeeID, line.Item, line.Quantity); err != nil {
+			logger.Error("Error adding item to inventory", zap.Error(err))
+			return entity.Cart{}, err
+		}
+		if err := repo.RecordPurchaseTx(ctx, tx, employeeID, line.Item, line.Price, line.Quantity); err != nil {
+			logger.Error("Error recording purchase", zap.Error(err))
+			return entity.Cart{}, err
+		}
+	}
+	return cart, nil
+}
+
+func (u *employeeUsecase) Authenticate(ctx context.Context, username, password string) (entity.TokenPair, error) {
+	u.logger.Info("Authenticate called", zap.String("username", username))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	employee, err := u.employeeRepo.GetEmployeeByUsername(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if !u.config.AutoProvision {
+			u.logger.Warn("User not found", zap.String("username", username))
+			return entity.TokenPair{}, ErrInvalidCredentials
+		}
+		u.logger.Warn("User not found, creating new user", zap.String("username", username))
+		return u.Register(ctx, username, password)
+	}
+	if err != nil {
+		u.logger.Error("Error getting employee by username", zap.Error(err))
+		return entity.TokenPair{}, err
+	}
+
+	ok, err := u.passwordHasher.Verify(employee.Password, password)
+	if err != nil {
+		u.logger.Error("Error verifying password", zap.Error(err))
+		return entity.TokenPair{}, ErrInvalidCredentials
+	}
+	if !ok {
 		u.logger.Warn("Invalid credentials", zap.String("username", username))
-		return "", errors.New("invalid credentials")
+		return entity.TokenPair{}, ErrInvalidCredentials
+	}
+
+	if u.passwordHasher.NeedsRehash(employee.Password) {
+		u.rehashPassword(ctx, employee.ID, password)
 	}
 
-	token, err := jwt.GenerateJWT(username)
+	tokens, err := u.tokenIssuer.IssueTokens(ctx, employee.ID, username)
 	if err != nil {
-		u.logger.Error("Error generating JWT", zap.Error(err))
-		return "", err
+		u.logger.Error("Error issuing tokens", zap.Error(err))
+		return entity.TokenPair{}, err
 	}
 
 	u.logger.Info("Successfully authenticated user", zap.String("username", username))
-	return token, nil
+	return tokens, nil
 }
 
-func (u *employeeUsecase) GetEmployeeIDByUsername(username string) (int, error) {
+func (u *employeeUsecase) Register(ctx context.Context, username, password string) (entity.TokenPair, error) {
+	u.logger.Info("Register called", zap.String("username", username))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if !usernamePattern.MatchString(username) {
+		return entity.TokenPair{}, ErrInvalidUsername
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return entity.TokenPair{}, ErrInvalidPassword
+	}
+
+	passwordHash, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		u.logger.Error("Error hashing password", zap.Error(err))
+		return entity.TokenPair{}, errors.New("failed to create new user")
+	}
+
+	newEmployee := entity.Employee{
+		Name:     username,
+		Password: passwordHash,
+		Coins:    u.config.StartingCoins,
+	}
+	employeeID, err := u.employeeRepo.CreateEmployee(ctx, newEmployee)
+	if errors.Is(err, repository.ErrEmployeeExists) {
+		u.logger.Warn("Username already taken", zap.String("username", username))
+		return entity.TokenPair{}, ErrUsernameTaken
+	}
+	if err != nil {
+		u.logger.Error("Failed to create new user", zap.Error(err))
+		return entity.TokenPair{}, errors.New("failed to create new user")
+	}
+
+	tokens, err := u.tokenIssuer.IssueTokens(ctx, employeeID, username)
+	if err != nil {
+		u.logger.Error("Error issuing tokens", zap.Error(err))
+		return entity.TokenPair{}, err
+	}
+
+	u.logger.Info("Successfully registered new user", zap.String("username", username))
+	return tokens, nil
+}
+
+// rehashPassword заменяет хэш другого алгоритма или с другими параметрами на хэш
+// текущего алгоритма. Ошибка не мешает входу: хэш обновится при следующем логине
+func (u *employeeUsecase) rehashPassword(ctx context.Context, employeeID int, password string) {
+	passwordHash, err := u.passwordHasher.Hash(password)
+	if err != nil {
+		u.logger.Warn("Error rehashing password", zap.Int("employeeID", employeeID), zap.Error(err))
+		return
+	}
+	if err := u.employeeRepo.UpdateEmployeePassword(ctx, employeeID, passwordHash); err != nil {
+		u.logger.Warn("Error storing rehashed password", zap.Int("employeeID", employeeID), zap.Error(err))
+		return
+	}
+	u.logger.Info("Password hash upgraded", zap.Int("employeeID", employeeID))
+}
+
+func (u *employeeUsecase) GetEmployeeIDByUsername(ctx context.Context, username string) (int, error) {
 	u.logger.Info("GetEmployeeIDByUsername called", zap.String("username", username))
-	employeeID, err := u.employeeRepo.GetEmployeeIDByUsername(username)
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	employeeID, err := u.employeeRepo.GetEmployeeIDByUsername(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrEmployeeNotFound
+	}
 	if err != nil {
 		u.logger.Error("Error getting employee ID by username", zap.Error(err))
 		return 0, err
@@ -197,3 +530,29 @@ func (u *employeeUsecase) GetEmployeeIDByUsername(username string) (int, error)
 	u.logger.Info("Successfully retrieved employee ID", zap.String("username", username), zap.Int("employeeID", employeeID))
 	return employeeID, nil
 }
+
+// SetEmployeeRole меняет роль сотрудника. Выданные ранее access-токены со
+// старой ролью перестают проходить проверку в AuthUsecase.Verify
+func (u *employeeUsecase) SetEmployeeRole(ctx context.Context, username, role string) error {
+	u.logger.Info("SetEmployeeRole called", zap.String("username", username), zap.String("role", role))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if !entity.IsValidRole(role) {
+		return ErrInvalidRole
+	}
+
+	employeeID, err := u.employeeRepo.GetEmployeeIDByUsername(ctx, username)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEmployeeNotFound
+	}
+	if err != nil {
+		u.logger.Error("Error getting employee ID by username", zap.Error(err))
+		return err
+	}
+
+	if err := u.employeeRepo.UpdateEmployeeRole(ctx, employeeID, role); err != nil {
+		return err
+	}
+	u.logger.Info("Successfully updated employee role", zap.String("username", username), zap.String("role", role))
+	return nil
+}
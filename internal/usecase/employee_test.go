@@ -3,37 +3,80 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v4"
 	"github.com/qosmioo/merch-store/internal/entity"
 	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/qosmioo/merch-store/pkg/hasher"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	testHasher = hasher.New(hasher.NewBcrypt(bcrypt.MinCost))
+	testIssuer = stubTokenIssuer{}
+	testConfig = EmployeeConfig{StartingCoins: DefaultStartingCoins}
+)
+
+type stubTokenIssuer struct{}
+
+func (stubTokenIssuer) IssueTokens(ctx context.Context, employeeID int, username string) (entity.TokenPair, error) {
+	return entity.TokenPair{AccessToken: "access-" + username, RefreshToken: "refresh-" + username}, nil
+}
+
 func TestGetEmployeeInfo(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	employeeID := 1
 	expectedEmployee := entity.Employee{ID: employeeID, Coins: 100}
 	expectedInventory := []entity.Inventory{{Type: "item1", Quantity: 1}}
 	expectedHistory := entity.CoinHistory{}
+	expectedPurchases := []entity.Purchase{{Item: "item1", Price: 10, CreatedAt: time.Now()}}
 
-	mockRepo.EXPECT().GetEmployeeByID(employeeID).Return(expectedEmployee, nil)
-	mockRepo.EXPECT().GetEmployeeInventory(employeeID).Return(expectedInventory, nil)
-	mockRepo.EXPECT().GetEmployeeCoinHistory(employeeID).Return(expectedHistory, nil)
+	mockRepo.EXPECT().GetEmployeeByID(ctx, employeeID).Return(expectedEmployee, nil)
+	mockRepo.EXPECT().GetEmployeeInventory(ctx, employeeID).Return(expectedInventory, nil)
+	mockRepo.EXPECT().GetEmployeeCoinHistory(ctx, employeeID).Return(expectedHistory, nil)
+	mockRepo.EXPECT().GetEmployeePurchases(ctx, employeeID).Return(expectedPurchases, nil)
 
-	info, err := usecase.GetEmployeeInfo(employeeID)
+	info, err := usecase.GetEmployeeInfo(ctx, employeeID)
 	assert.NoError(t, err)
 	assert.Equal(t, expectedEmployee.Coins, info.Coins)
 	assert.Equal(t, expectedInventory, info.Inventory)
 	assert.Equal(t, expectedHistory, info.CoinHistory)
+	assert.Equal(t, expectedPurchases, info.Purchases)
+}
+
+func TestGetEmployeeInfo_QueryTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	config := testConfig
+	config.QueryTimeout = time.Second
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, config, logger)
+
+	mockRepo.EXPECT().GetEmployeeByID(gomock.Any(), 1).DoAndReturn(func(ctx context.Context, _ int) (entity.Employee, error) {
+		deadline, ok := ctx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+		return entity.Employee{}, context.DeadlineExceeded
+	})
+
+	_, err := usecase.GetEmployeeInfo(context.Background(), 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
 }
 
 func TestTransferCoins_Success(t *testing.T) {
@@ -43,7 +86,8 @@ func TestTransferCoins_Success(t *testing.T) {
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
 	mockTx := repository.NewMockTransaction(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	fromEmployeeID := 1
 	toEmployeeID := 2
@@ -51,15 +95,19 @@ func TestTransferCoins_Success(t *testing.T) {
 	fromEmployee := entity.Employee{ID: fromEmployeeID, Coins: 100}
 	toEmployee := entity.Employee{ID: toEmployeeID, Coins: 50}
 
-	mockRepo.EXPECT().BeginTransaction().Return(mockTx, nil)
-	mockRepo.EXPECT().GetEmployeeByIDTx(mockTx, fromEmployeeID).Return(fromEmployee, nil)
-	mockRepo.EXPECT().GetEmployeeByIDTx(mockTx, toEmployeeID).Return(toEmployee, nil)
-	mockRepo.EXPECT().UpdateEmployeeCoinsTx(mockTx, fromEmployeeID, fromEmployee.Coins-amount).Return(nil)
-	mockRepo.EXPECT().UpdateEmployeeCoinsTx(mockTx, toEmployeeID, toEmployee.Coins+amount).Return(nil)
-	mockRepo.EXPECT().RecordTransactionTx(mockTx, fromEmployeeID, toEmployeeID, amount).Return(nil)
-	mockTx.EXPECT().Commit(context.Background()).Return(nil)
-
-	err := usecase.TransferCoins(fromEmployeeID, toEmployeeID, amount)
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().LockEmployeesTx(ctx, mockTx, fromEmployeeID, toEmployeeID).Return([]entity.Employee{fromEmployee, toEmployee}, nil)
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, entity.JournalEntry{
+		Kind: entity.EntryTransfer,
+		Postings: []entity.Posting{
+			{Account: entity.EmployeeAccount(fromEmployeeID), Amount: -amount},
+			{Account: entity.EmployeeAccount(toEmployeeID), Amount: amount},
+		},
+	}).Return(nil)
+	mockRepo.EXPECT().RecordTransactionTx(ctx, mockTx, fromEmployeeID, toEmployeeID, amount, entity.Memo{Message: "Спасибо за помощь", Public: true}).Return(nil)
+	mockTx.EXPECT().Commit(ctx).Return(nil)
+
+	err := usecase.TransferCoins(ctx, fromEmployeeID, toEmployeeID, amount, entity.Memo{Message: "  Спасибо\nза помощь\u202e ", Public: true})
 	assert.NoError(t, err)
 }
 
@@ -70,22 +118,82 @@ func TestTransferCoins_InsufficientFunds(t *testing.T) {
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
 	mockTx := repository.NewMockTransaction(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	fromEmployeeID := 1
 	toEmployeeID := 2
 	amount := 150
 	fromEmployee := entity.Employee{ID: fromEmployeeID, Coins: 100}
 
-	mockRepo.EXPECT().BeginTransaction().Return(mockTx, nil)
-	mockRepo.EXPECT().GetEmployeeByIDTx(mockTx, fromEmployeeID).Return(fromEmployee, nil)
-	mockTx.EXPECT().Rollback(context.Background()).Return(nil)
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().LockEmployeesTx(ctx, mockTx, fromEmployeeID, toEmployeeID).Return([]entity.Employee{fromEmployee, {ID: toEmployeeID}}, nil)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
 
-	err := usecase.TransferCoins(fromEmployeeID, toEmployeeID, amount)
+	err := usecase.TransferCoins(ctx, fromEmployeeID, toEmployeeID, amount, entity.Memo{})
 	assert.Error(t, err)
 	assert.Equal(t, "insufficient coins", err.Error())
 }
 
+func TestTransferCoins_SelfTransfer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	err := usecase.TransferCoins(ctx, 1, 1, 10, entity.Memo{})
+	assert.ErrorIs(t, err, ErrSelfTransfer)
+}
+
+func TestTransferCoins_InvalidAmount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	for _, amount := range []int{0, -10} {
+		err := usecase.TransferCoins(ctx, 1, 2, amount, entity.Memo{})
+		assert.ErrorIs(t, err, ErrInvalidAmount)
+	}
+}
+
+func TestTransferCoins_MessageTooLong(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	err := usecase.TransferCoins(ctx, 1, 2, 10, entity.Memo{Message: strings.Repeat("я", maxMessageLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidMessage)
+}
+
+func TestSanitizeMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"", ""},
+		{"  thanks  ", "thanks"},
+		{"line\r\nbreak\t tab", "line break tab"},
+		{"bell\a and null\x00", "bell and null"},
+		{"evil\u202etxt.exe", "eviltxt.exe"},
+		{"zero\u200bwidth", "zerowidth"},
+		{"<b>html</b>", "<b>html</b>"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, sanitizeMessage(tt.message), "message %q", tt.message)
+	}
+}
+
 func TestTransferCoins_NonExistentRecipient(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -93,21 +201,76 @@ func TestTransferCoins_NonExistentRecipient(t *testing.T) {
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
 	mockTx := repository.NewMockTransaction(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	fromEmployeeID := 1
 	toEmployeeID := 999
 	amount := 50
 	fromEmployee := entity.Employee{ID: fromEmployeeID, Coins: 100}
 
-	mockRepo.EXPECT().BeginTransaction().Return(mockTx, nil)
-	mockRepo.EXPECT().GetEmployeeByIDTx(mockTx, fromEmployeeID).Return(fromEmployee, nil)
-	mockRepo.EXPECT().GetEmployeeByIDTx(mockTx, toEmployeeID).Return(entity.Employee{}, errors.New("employee not found"))
-	mockTx.EXPECT().Rollback(context.Background()).Return(nil)
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().LockEmployeesTx(ctx, mockTx, fromEmployeeID, toEmployeeID).Return([]entity.Employee{fromEmployee}, nil)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
 
-	err := usecase.TransferCoins(fromEmployeeID, toEmployeeID, amount)
-	assert.Error(t, err)
-	assert.Equal(t, "employee not found", err.Error())
+	err := usecase.TransferCoins(ctx, fromEmployeeID, toEmployeeID, amount, entity.Memo{})
+	assert.ErrorIs(t, err, ErrRecipientNotFound)
+}
+
+func TestTransferCoins_CommitFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, zap.NewNop())
+	ctx := context.Background()
+	commitErr := errors.New("could not serialize access")
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().LockEmployeesTx(ctx, mockTx, 1, 2).Return([]entity.Employee{{ID: 1, Coins: 100}, {ID: 2}}, nil)
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, gomock.Any()).Return(nil)
+	mockRepo.EXPECT().RecordTransactionTx(ctx, mockTx, 1, 2, 50, entity.Memo{}).Return(nil)
+	mockTx.EXPECT().Commit(ctx).Return(commitErr)
+
+	// Незафиксированный перевод не должен выглядеть для клиента успешным
+	err := usecase.TransferCoins(ctx, 1, 2, 50, entity.Memo{})
+	assert.ErrorIs(t, err, commitErr)
+}
+
+func TestBuyMerch_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	employeeID := 1
+	itemName := "cup"
+	price := 20
+	employee := entity.Employee{ID: employeeID, Coins: 100}
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, itemName).Return(price, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, itemName, 1).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, employeeID).Return(employee, nil)
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, entity.JournalEntry{
+		Kind:        entity.EntryPurchase,
+		Description: itemName,
+		Postings: []entity.Posting{
+			{Account: entity.EmployeeAccount(employeeID), Amount: -price},
+			{Account: entity.StoreAccount, Amount: price},
+		},
+	}).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, employeeID, itemName, 1).Return(nil)
+	mockRepo.EXPECT().RecordPurchaseTx(ctx, mockTx, employeeID, itemName, price, 1).Return(nil)
+	mockTx.EXPECT().Commit(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, employeeID, itemName, 1)
+	assert.NoError(t, err)
 }
 
 func TestBuyMerch_InsufficientFunds(t *testing.T) {
@@ -115,19 +278,165 @@ func TestBuyMerch_InsufficientFunds(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	employeeID := 1
 	itemName := "item1"
 	employee := entity.Employee{ID: employeeID, Coins: 30}
 
-	mockRepo.EXPECT().GetMerchPrice(itemName).Return(50, nil)
-	mockRepo.EXPECT().GetEmployeeByID(employeeID).Return(employee, nil)
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, itemName).Return(50, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, itemName, 1).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, employeeID).Return(employee, nil)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, employeeID, itemName, 1)
+	assert.ErrorIs(t, err, ErrInsufficientCoins)
+}
+
+func TestBuyMerch_UnknownItem(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "unknown").Return(0, pgx.ErrNoRows)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, 1, "unknown", 1)
+	assert.ErrorIs(t, err, ErrItemNotFound)
+}
+
+func TestBuyMerch_OutOfStock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "cup").Return(20, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "cup", 1).Return(repository.ErrOutOfStock)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, 1, "cup", 1)
+	assert.ErrorIs(t, err, ErrOutOfStock)
+}
+
+func TestBuyMerch_Quantity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	employeeID := 1
+	itemName := "cup"
+	price := 20
+	employee := entity.Employee{ID: employeeID, Coins: 100}
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, itemName).Return(price, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, itemName, 3).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, employeeID).Return(employee, nil)
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, entity.JournalEntry{
+		Kind:        entity.EntryPurchase,
+		Description: itemName,
+		Postings: []entity.Posting{
+			{Account: entity.EmployeeAccount(employeeID), Amount: -60},
+			{Account: entity.StoreAccount, Amount: 60},
+		},
+	}).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, employeeID, itemName, 3).Return(nil)
+	mockRepo.EXPECT().RecordPurchaseTx(ctx, mockTx, employeeID, itemName, price, 3).Return(nil)
+	mockTx.EXPECT().Commit(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, employeeID, itemName, 3)
+	assert.NoError(t, err)
+}
+
+func TestBuyMerch_QuantityExceedsBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	// Одна единица по карману, пять — уже нет
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, "cup").Return(20, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, "cup", 5).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, 1).Return(entity.Employee{ID: 1, Coins: 90}, nil)
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, 1, "cup", 5)
+	assert.ErrorIs(t, err, ErrInsufficientCoins)
+}
+
+func TestBuyMerch_InvalidQuantity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, zap.NewNop())
+	ctx := context.Background()
+
+	for _, quantity := range []int{0, -1, MaxPurchaseQuantity + 1} {
+		err := usecase.BuyMerch(ctx, 1, "cup", quantity)
+		assert.ErrorIs(t, err, ErrInvalidQuantity)
+	}
+}
+
+func TestBuyMerch_InventoryFailureRollsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	mockTx := repository.NewMockTransaction(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
-	err := usecase.BuyMerch(employeeID, itemName)
+	employeeID := 1
+	itemName := "cup"
+	price := 20
+	employee := entity.Employee{ID: employeeID, Coins: 100}
+
+	mockRepo.EXPECT().BeginTransaction(ctx).Return(mockTx, nil)
+	mockRepo.EXPECT().GetMerchPriceTx(ctx, mockTx, itemName).Return(price, nil)
+	mockRepo.EXPECT().DecrementMerchStockTx(ctx, mockTx, itemName, 1).Return(nil)
+	mockRepo.EXPECT().GetEmployeeByIDTx(ctx, mockTx, employeeID).Return(employee, nil)
+	mockRepo.EXPECT().PostJournalEntryTx(ctx, mockTx, entity.JournalEntry{
+		Kind:        entity.EntryPurchase,
+		Description: itemName,
+		Postings: []entity.Posting{
+			{Account: entity.EmployeeAccount(employeeID), Amount: -price},
+			{Account: entity.StoreAccount, Amount: price},
+		},
+	}).Return(nil)
+	mockRepo.EXPECT().AddToInventoryTx(ctx, mockTx, employeeID, itemName, 1).Return(errors.New("inventory insert failed"))
+	mockTx.EXPECT().Rollback(ctx).Return(nil)
+
+	err := usecase.BuyMerch(ctx, employeeID, itemName, 1)
 	assert.Error(t, err)
-	assert.Equal(t, "insufficient coins", err.Error())
+	assert.Equal(t, "inventory insert failed", err.Error())
 }
 
 func TestAuthenticate(t *testing.T) {
@@ -136,35 +445,294 @@ func TestAuthenticate(t *testing.T) {
 
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	username := "testuser"
 	password := "password"
-	employee := entity.Employee{Name: username, Password: password, Coins: 1000}
+	passwordHash, err := testHasher.Hash(password)
+	assert.NoError(t, err)
+	employee := entity.Employee{ID: 1, Name: username, Password: passwordHash, Coins: 1000}
 
-	mockRepo.EXPECT().GetEmployeeByUsername(username).Return(employee, nil)
+	mockRepo.EXPECT().GetEmployeeByUsername(ctx, username).Return(employee, nil)
 
-	token, err := usecase.Authenticate(username, password)
+	token, err := usecase.Authenticate(ctx, username, password)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 }
 
+func TestAuthenticate_RehashesLegacyPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	username := "testuser"
+	password := "password"
+	// Хэш с другой стоимостью bcrypt считается устаревшим
+	legacyHash, err := hasher.NewBcrypt(bcrypt.MinCost + 1).Hash(password)
+	require.NoError(t, err)
+	employee := entity.Employee{ID: 1, Name: username, Password: legacyHash, Coins: 1000}
+
+	var storedHash string
+	mockRepo.EXPECT().GetEmployeeByUsername(ctx, username).Return(employee, nil)
+	mockRepo.EXPECT().UpdateEmployeePassword(ctx, employee.ID, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, passwordHash string) error {
+		storedHash = passwordHash
+		return nil
+	})
+
+	token, err := usecase.Authenticate(ctx, username, password)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	ok, err := testHasher.Verify(storedHash, password)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, testHasher.NeedsRehash(storedHash))
+}
+
+func TestAuthenticate_UnknownUserIsNotCreated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().GetEmployeeByUsername(ctx, "typo").Return(entity.Employee{}, pgx.ErrNoRows)
+
+	token, err := usecase.Authenticate(ctx, "typo", "password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Empty(t, token)
+}
+
+func TestAuthenticate_AutoProvision(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	config := EmployeeConfig{AutoProvision: true, StartingCoins: DefaultStartingCoins}
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, config, logger)
+	ctx := context.Background()
+
+	username := "newuser"
+	password := "password"
+
+	mockRepo.EXPECT().GetEmployeeByUsername(ctx, username).Return(entity.Employee{}, pgx.ErrNoRows)
+	mockRepo.EXPECT().CreateEmployee(ctx, gomock.Any()).Return(1, nil)
+
+	token, err := usecase.Authenticate(ctx, username, password)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+}
+
+func TestRegister_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	username := "newuser"
+	password := "password"
+
+	mockRepo.EXPECT().CreateEmployee(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, employee entity.Employee) (int, error) {
+		assert.Equal(t, username, employee.Name)
+		assert.Equal(t, DefaultStartingCoins, employee.Coins)
+		assert.NotEqual(t, password, employee.Password)
+		ok, err := testHasher.Verify(employee.Password, password)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		return 1, nil
+	})
+
+	token, err := usecase.Register(ctx, username, password)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+}
+
+func TestRegister_Validation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		err      error
+	}{
+		{"short username", "ab", "password", ErrInvalidUsername},
+		{"username with spaces", "john doe", "password", ErrInvalidUsername},
+		{"short password", "john", "short", ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := usecase.Register(ctx, tt.username, tt.password)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Empty(t, token)
+		})
+	}
+}
+
+func TestRegister_UsernameTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().CreateEmployee(ctx, gomock.Any()).Return(0, repository.ErrEmployeeExists)
+
+	token, err := usecase.Register(ctx, "existing", "password")
+	assert.ErrorIs(t, err, ErrUsernameTaken)
+	assert.Empty(t, token)
+}
+
 func TestAuthenticate_InvalidCredentials(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockRepo := repository.NewMockEmployeeRepository(ctrl)
 	logger := zap.NewNop()
-	usecase := NewEmployeeUsecase(mockRepo, logger)
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
 
 	username := "testuser"
 	password := "wrongpassword"
 	employee := entity.Employee{Name: username, Password: "correctpassword", Coins: 1000}
 
-	mockRepo.EXPECT().GetEmployeeByUsername(username).Return(employee, nil)
+	mockRepo.EXPECT().GetEmployeeByUsername(ctx, username).Return(employee, nil)
 
-	token, err := usecase.Authenticate(username, password)
+	token, err := usecase.Authenticate(ctx, username, password)
 	assert.Error(t, err)
 	assert.Equal(t, "invalid credentials", err.Error())
 	assert.Empty(t, token)
 }
+
+func TestSetEmployeeRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	mockRepo.EXPECT().GetEmployeeIDByUsername(ctx, "alice").Return(1, nil)
+	mockRepo.EXPECT().UpdateEmployeeRole(ctx, 1, entity.RoleAuditor).Return(nil)
+
+	assert.NoError(t, usecase.SetEmployeeRole(ctx, "alice", entity.RoleAuditor))
+}
+
+func TestSetEmployeeRole_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, usecase.SetEmployeeRole(ctx, "alice", "superuser"), ErrInvalidRole)
+
+	mockRepo.EXPECT().GetEmployeeIDByUsername(ctx, "nobody").Return(0, pgx.ErrNoRows)
+	assert.ErrorIs(t, usecase.SetEmployeeRole(ctx, "nobody", entity.RoleAdmin), ErrEmployeeNotFound)
+}
+
+func TestGetCoinHistory_Pagination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	employeeID := 1
+	filter := entity.HistoryFilter{Direction: entity.DirectionSent}
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	entries := []entity.HistoryEntry{
+		{ID: 3, Direction: entity.DirectionSent, FromUser: "sam", ToUser: "alex", Amount: 30, CreatedAt: now},
+		{ID: 2, Direction: entity.DirectionSent, FromUser: "sam", ToUser: "alex", Amount: 20, CreatedAt: now.Add(-time.Minute)},
+		{ID: 1, Direction: entity.DirectionSent, FromUser: "sam", ToUser: "alex", Amount: 10, CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	mockRepo.EXPECT().GetEmployeeHistory(ctx, employeeID, filter, nil, 3).Return(entries, nil)
+	page, err := usecase.GetCoinHistory(ctx, employeeID, filter, "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, entries[:2], page.Items)
+	require.NotEmpty(t, page.NextCursor)
+
+	// Курсор указывает на последнюю запись первой страницы
+	after := &entity.HistoryCursor{CreatedAt: entries[1].CreatedAt, ID: entries[1].ID}
+	mockRepo.EXPECT().GetEmployeeHistory(ctx, employeeID, filter, after, 3).Return(entries[2:], nil)
+	page, err = usecase.GetCoinHistory(ctx, employeeID, filter, page.NextCursor, 2)
+	require.NoError(t, err)
+	assert.Equal(t, entries[2:], page.Items)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetCoinHistory_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	now := time.Now()
+	tests := []struct {
+		name   string
+		filter entity.HistoryFilter
+		cursor string
+		limit  int
+		err    error
+	}{
+		{"unknown direction", entity.HistoryFilter{Direction: "both"}, "", 0, ErrInvalidHistory},
+		{"from after to", entity.HistoryFilter{From: now, To: now.Add(-time.Hour)}, "", 0, ErrInvalidHistory},
+		{"limit too large", entity.HistoryFilter{}, "", MaxHistoryLimit + 1, ErrInvalidHistory},
+		{"negative limit", entity.HistoryFilter{}, "", -1, ErrInvalidHistory},
+		{"malformed cursor", entity.HistoryFilter{}, "not-a-cursor", 0, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usecase.GetCoinHistory(ctx, 1, tt.filter, tt.cursor, tt.limit)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestListKudos(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockEmployeeRepository(ctrl)
+	logger := zap.NewNop()
+	usecase := NewEmployeeUsecase(mockRepo, testHasher, testIssuer, testConfig, logger)
+	ctx := context.Background()
+
+	kudos := []entity.Kudos{{ID: 1, FromUser: "sam", ToUser: "alex", Amount: 10, Message: "за ревью"}}
+	mockRepo.EXPECT().ListKudos(ctx, DefaultHistoryLimit).Return(kudos, nil)
+
+	result, err := usecase.ListKudos(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, kudos, result)
+
+	_, err = usecase.ListKudos(ctx, MaxHistoryLimit+1)
+	assert.ErrorIs(t, err, ErrInvalidKudosLimit)
+}
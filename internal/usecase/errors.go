@@ -0,0 +1,29 @@
+package usecase
+
+// ErrorKind классифицирует ошибки сценариев. По нему слой доставки выбирает
+// код ответа, не завися от конкретных ошибок
+type ErrorKind int
+
+const (
+	// KindInvalid — некорректный запрос или операция, недопустимая в текущем состоянии
+	KindInvalid ErrorKind = iota + 1
+	KindUnauthorized
+	KindNotFound
+	KindConflict
+)
+
+// Error — ожидаемая ошибка сценария. Её текст предназначен для клиента,
+// поэтому в нём не должно быть деталей хранилища. Любая другая ошибка
+// считается внутренней
+type Error struct {
+	Kind    ErrorKind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(kind ErrorKind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"go.uber.org/zap"
+)
+
+// DefaultIdempotencyTTL — время хранения ответа на запрос с Idempotency-Key
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyLease — сколько ключ остаётся занятым незавершённым
+// запросом. Должно превышать время обработки самого долгого запроса
+const DefaultIdempotencyLease = 2 * time.Minute
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey = newError(KindInvalid, "Idempotency-Key must be 1-255 printable ASCII characters")
+	ErrIdempotencyKeyReused  = newError(KindInvalid, "Idempotency-Key has already been used for a different request")
+	ErrIdempotencyInProgress = newError(KindConflict, "request with this Idempotency-Key is still in progress")
+)
+
+// IdempotencyUsecase хранит ответы на мутирующие запросы, чтобы повтор запроса
+// с тем же Idempotency-Key возвращал исходный ответ, а не выполнялся заново
+type IdempotencyUsecase interface {
+	// Begin занимает ключ для запроса с отпечатком fingerprint. Если запрос уже
+	// выполнен, возвращает сохранённый ответ, который нужно отдать клиенту
+	Begin(ctx context.Context, employeeID int, key, fingerprint string) (*entity.IdempotentResponse, error)
+	Complete(ctx context.Context, employeeID int, key string, response entity.IdempotentResponse) error
+	// Release освобождает ключ, если ответ не нужно сохранять и запрос можно повторить
+	Release(ctx context.Context, employeeID int, key string) error
+	// CleanupExpired удаляет ключи старше TTL и возвращает их количество
+	CleanupExpired(ctx context.Context) (int64, error)
+	// RunCleanup вызывает CleanupExpired каждые interval до отмены ctx
+	RunCleanup(ctx context.Context, interval time.Duration)
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration
+	// Lease — через сколько ключ незавершённого запроса может занять повтор
+	Lease time.Duration
+}
+
+type idempotencyUsecase struct {
+	idempotencyRepo repository.IdempotencyRepository
+	config          IdempotencyConfig
+	logger          *zap.Logger
+}
+
+func NewIdempotencyUsecase(idempotencyRepo repository.IdempotencyRepository, config IdempotencyConfig, logger *zap.Logger) IdempotencyUsecase {
+	if config.TTL <= 0 {
+		config.TTL = DefaultIdempotencyTTL
+	}
+	if config.Lease <= 0 {
+		config.Lease = DefaultIdempotencyLease
+	}
+	return &idempotencyUsecase{idempotencyRepo: idempotencyRepo, config: config, logger: logger}
+}
+
+func (u *idempotencyUsecase) Begin(ctx context.Context, employeeID int, key, fingerprint string) (*entity.IdempotentResponse, error) {
+	u.logger.Info("Begin idempotent request", zap.Int("employeeID", employeeID), zap.String("key", key))
+	if !validIdempotencyKey(key) {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	now := time.Now()
+	reserved, err := u.idempotencyRepo.ReserveKey(ctx, employeeID, key, fingerprint, now.Add(-u.config.TTL), now.Add(-u.config.Lease))
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	stored, err := u.idempotencyRepo.GetKey(ctx, employeeID, key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Ключ освободили между попыткой занять его и чтением
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored.Fingerprint != fingerprint {
+		u.logger.Warn("Idempotency key reused for a different request", zap.Int("employeeID", employeeID), zap.String("key", key))
+		return nil, ErrIdempotencyKeyReused
+	}
+	if stored.Status == 0 {
+		return nil, ErrIdempotencyInProgress
+	}
+	u.logger.Info("Replaying idempotent response", zap.Int("employeeID", employeeID), zap.String("key", key), zap.Int("status", stored.Status))
+	return &stored, nil
+}
+
+func (u *idempotencyUsecase) Complete(ctx context.Context, employeeID int, key string, response entity.IdempotentResponse) error {
+	return u.idempotencyRepo.CompleteKey(ctx, employeeID, key, response)
+}
+
+func (u *idempotencyUsecase) Release(ctx context.Context, employeeID int, key string) error {
+	return u.idempotencyRepo.ReleaseKey(ctx, employeeID, key)
+}
+
+func (u *idempotencyUsecase) CleanupExpired(ctx context.Context) (int64, error) {
+	deleted, err := u.idempotencyRepo.DeleteExpiredKeys(ctx, time.Now().Add(-u.config.TTL))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		u.logger.Info("Deleted expired idempotency keys", zap.Int64("count", deleted))
+	}
+	return deleted, nil
+}
+
+func (u *idempotencyUsecase) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := u.CleanupExpired(ctx); err != nil {
+				u.logger.Error("Error cleaning up idempotency keys", zap.Error(err))
+			}
+		}
+	}
+}
+
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestIdempotencyBegin(t *testing.T) {
+	stored := entity.IdempotentResponse{Fingerprint: "abc", Status: 200, ContentType: "application/json", Body: []byte(`{}`)}
+	tests := []struct {
+		name     string
+		reserved bool
+		stored   entity.IdempotentResponse
+		getErr   error
+		replay   *entity.IdempotentResponse
+		err      error
+	}{
+		{name: "new key", reserved: true},
+		{name: "completed request is replayed", stored: stored, replay: &stored},
+		{name: "different request", stored: entity.IdempotentResponse{Fingerprint: "other", Status: 200}, err: ErrIdempotencyKeyReused},
+		{name: "request in progress", stored: entity.IdempotentResponse{Fingerprint: "abc"}, err: ErrIdempotencyInProgress},
+		{name: "key released concurrently", getErr: pgx.ErrNoRows, err: ErrIdempotencyInProgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := repository.NewMockIdempotencyRepository(ctrl)
+			usecase := NewIdempotencyUsecase(mockRepo, IdempotencyConfig{TTL: time.Hour}, zap.NewNop())
+			ctx := context.Background()
+
+			mockRepo.EXPECT().ReserveKey(ctx, 1, "key-1", "abc", gomock.Any(), gomock.Any()).Return(tt.reserved, nil)
+			if !tt.reserved {
+				mockRepo.EXPECT().GetKey(ctx, 1, "key-1").Return(tt.stored, tt.getErr)
+			}
+
+			replay, err := usecase.Begin(ctx, 1, "key-1", "abc")
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.replay, replay)
+		})
+	}
+}
+
+func TestIdempotencyBegin_TakesOverStaleReservation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockIdempotencyRepository(ctrl)
+	usecase := NewIdempotencyUsecase(mockRepo, IdempotencyConfig{TTL: time.Hour, Lease: time.Minute}, zap.NewNop())
+	ctx := context.Background()
+
+	// Незавершённый запрос держит ключ не дольше аренды, а не весь TTL
+	mockRepo.EXPECT().ReserveKey(ctx, 1, "key-1", "abc", gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, employeeID int, key, fingerprint string, expiredBefore, staleBefore time.Time) (bool, error) {
+			assert.WithinDuration(t, time.Now().Add(-time.Hour), expiredBefore, time.Second)
+			assert.WithinDuration(t, time.Now().Add(-time.Minute), staleBefore, time.Second)
+			return true, nil
+		})
+
+	replay, err := usecase.Begin(ctx, 1, "key-1", "abc")
+	require.NoError(t, err)
+	assert.Nil(t, replay)
+}
+
+func TestIdempotencyBegin_InvalidKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockIdempotencyRepository(ctrl)
+	usecase := NewIdempotencyUsecase(mockRepo, IdempotencyConfig{}, zap.NewNop())
+	ctx := context.Background()
+
+	for _, key := range []string{"", strings.Repeat("k", 256), "ключ", "key\n"} {
+		_, err := usecase.Begin(ctx, 1, key, "abc")
+		assert.Equal(t, ErrInvalidIdempotencyKey, err, key)
+	}
+}
+
+func TestIdempotencyCleanupExpired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockIdempotencyRepository(ctrl)
+	usecase := NewIdempotencyUsecase(mockRepo, IdempotencyConfig{TTL: time.Hour}, zap.NewNop())
+	ctx := context.Background()
+
+	before := time.Now().Add(-time.Hour)
+	mockRepo.EXPECT().DeleteExpiredKeys(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, expiredBefore time.Time) (int64, error) {
+		assert.WithinDuration(t, before, expiredBefore, time.Second)
+		return 3, nil
+	})
+
+	deleted, err := usecase.CleanupExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
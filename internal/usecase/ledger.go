@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"go.uber.org/zap"
+)
+
+type LedgerUsecase interface {
+	// CheckLedger проверяет, что монеты не появляются и не исчезают вне проводок журнала
+	CheckLedger(ctx context.Context) (entity.LedgerReport, error)
+}
+
+type ledgerUsecase struct {
+	ledgerRepo repository.LedgerRepository
+	logger     *zap.Logger
+}
+
+func NewLedgerUsecase(ledgerRepo repository.LedgerRepository, logger *zap.Logger) LedgerUsecase {
+	return &ledgerUsecase{ledgerRepo: ledgerRepo, logger: logger}
+}
+
+func (u *ledgerUsecase) CheckLedger(ctx context.Context) (entity.LedgerReport, error) {
+	u.logger.Info("CheckLedger called")
+	balances, err := u.ledgerRepo.GetBalances(ctx)
+	if err != nil {
+		return entity.LedgerReport{}, err
+	}
+	unbalanced, err := u.ledgerRepo.ListUnbalancedEntries(ctx)
+	if err != nil {
+		return entity.LedgerReport{}, err
+	}
+	mismatches, err := u.ledgerRepo.ListBalanceMismatches(ctx)
+	if err != nil {
+		return entity.LedgerReport{}, err
+	}
+
+	// Счёт эмиссии уходит в минус на количество выпущенных монет
+	report := entity.LedgerReport{
+		Issued:            -balances[entity.AccountIssuance],
+		Employees:         balances[entity.AccountEmployee],
+		Store:             balances[entity.AccountStore],
+		UnbalancedEntries: unbalanced,
+		BalanceMismatches: mismatches,
+	}
+	report.Conserved = report.Issued == report.Employees+report.Store && len(unbalanced) == 0 && len(mismatches) == 0
+	if !report.Conserved {
+		u.logger.Error("Ledger invariants violated", zap.Any("report", report))
+	}
+	return report, nil
+}
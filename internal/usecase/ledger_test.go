@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCheckLedger(t *testing.T) {
+	tests := []struct {
+		name       string
+		balances   map[string]int
+		unbalanced []int
+		mismatches []entity.BalanceMismatch
+		conserved  bool
+	}{
+		{
+			name:      "conserved",
+			balances:  map[string]int{entity.AccountIssuance: -3000, entity.AccountEmployee: 2950, entity.AccountStore: 50},
+			conserved: true,
+		},
+		{
+			name:      "coins appeared outside ledger",
+			balances:  map[string]int{entity.AccountIssuance: -3000, entity.AccountEmployee: 3000, entity.AccountStore: 50},
+			conserved: false,
+		},
+		{
+			name:       "unbalanced entry",
+			balances:   map[string]int{entity.AccountIssuance: -3000, entity.AccountEmployee: 3000},
+			unbalanced: []int{7},
+			conserved:  false,
+		},
+		{
+			name:       "cached balance differs",
+			balances:   map[string]int{entity.AccountIssuance: -1000, entity.AccountEmployee: 1000},
+			mismatches: []entity.BalanceMismatch{{EmployeeID: 1, Cached: 1200, Ledger: 1000}},
+			conserved:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := repository.NewMockLedgerRepository(ctrl)
+			usecase := NewLedgerUsecase(mockRepo, zap.NewNop())
+			ctx := context.Background()
+
+			mockRepo.EXPECT().GetBalances(ctx).Return(tt.balances, nil)
+			mockRepo.EXPECT().ListUnbalancedEntries(ctx).Return(tt.unbalanced, nil)
+			mockRepo.EXPECT().ListBalanceMismatches(ctx).Return(tt.mismatches, nil)
+
+			report, err := usecase.CheckLedger(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, tt.conserved, report.Conserved)
+			assert.Equal(t, -tt.balances[entity.AccountIssuance], report.Issued)
+		})
+	}
+}
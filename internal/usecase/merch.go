@@ -0,0 +1,255 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"go.uber.org/zap"
+)
+
+type MerchUsecase interface {
+	// ListCatalog возвращает товары в продаже для публичного каталога
+	ListCatalog(ctx context.Context, filter entity.MerchFilter) ([]entity.Merch, error)
+	ListMerch(ctx context.Context, includeDeleted bool) ([]entity.Merch, error)
+	GetMerch(ctx context.Context, merchID int) (entity.Merch, error)
+	CreateMerch(ctx context.Context, merch entity.Merch) (entity.Merch, error)
+	UpdateMerch(ctx context.Context, merch entity.Merch) (entity.Merch, error)
+	DeleteMerch(ctx context.Context, merchID int) error
+	RestockMerch(ctx context.Context, merchID, quantity, employeeID int, comment string) (entity.Merch, error)
+	GetStockReport(ctx context.Context, merchID int) (entity.StockReport, error)
+}
+
+// Название товара используется в пути /api/buy/{item}, поэтому допускаются только безопасные для URL символы
+var merchNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,100}$`)
+
+var merchCategoryPattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{0,50}$`)
+
+const (
+	maxMerchDescriptionLength = 1000
+	maxRestockQuantity        = 100000
+	maxRestockCommentLength   = 500
+)
+
+var (
+	ErrInvalidMerchName        = newError(KindInvalid, "merch name must be 1-100 characters long and contain only letters, digits, '.', '_' or '-'")
+	ErrInvalidMerchCategory    = newError(KindInvalid, "merch category must be at most 50 characters long and contain only letters, digits, '.', '_' or '-'")
+	ErrInvalidMerchDescription = newError(KindInvalid, "merch description must be at most 1000 characters long")
+	ErrInvalidPrice            = newError(KindInvalid, "price must be positive")
+	ErrInvalidRestock          = newError(KindInvalid, "restock quantity must be between 1 and 100000 and comment at most 500 characters long")
+	ErrInvalidMerchFilter      = newError(KindInvalid, "invalid catalog filter: prices must be non-negative, minPrice must not exceed maxPrice, sort must be one of price, -price, name, -name")
+	ErrMerchExists             = newError(KindConflict, "merch already exists")
+)
+
+// merchSorts — допустимые значения MerchFilter.Sort и соответствующие им сравнения
+var merchSorts = map[string]func(a, b entity.Merch) bool{
+	"price":  func(a, b entity.Merch) bool { return a.Price < b.Price },
+	"-price": func(a, b entity.Merch) bool { return a.Price > b.Price },
+	"name":   func(a, b entity.Merch) bool { return a.Name < b.Name },
+	"-name":  func(a, b entity.Merch) bool { return a.Name > b.Name },
+}
+
+type MerchConfig struct {
+	// QueryTimeout ограничивает время обращения к базе данных, как EmployeeConfig.QueryTimeout
+	QueryTimeout time.Duration
+}
+
+type merchUsecase struct {
+	merchRepo repository.MerchRepository
+	config    MerchConfig
+	logger    *zap.Logger
+}
+
+func NewMerchUsecase(merchRepo repository.MerchRepository, config MerchConfig, logger *zap.Logger) MerchUsecase {
+	return &merchUsecase{merchRepo: merchRepo, config: config, logger: logger}
+}
+
+func (u *merchUsecase) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if u.config.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, u.config.QueryTimeout)
+}
+
+func (u *merchUsecase) ListCatalog(ctx context.Context, filter entity.MerchFilter) ([]entity.Merch, error) {
+	u.logger.Info("ListCatalog called", zap.Any("filter", filter))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	less, sortable := merchSorts[filter.Sort]
+	if filter.MinPrice < 0 || filter.MaxPrice < 0 || (filter.MaxPrice > 0 && filter.MinPrice > filter.MaxPrice) || (filter.Sort != "" && !sortable) {
+		return nil, ErrInvalidMerchFilter
+	}
+
+	merch, err := u.merchRepo.ListMerch(ctx, false)
+	if err != nil {
+		u.logger.Error("Error listing merch", zap.Error(err))
+		return nil, err
+	}
+
+	// Каталог небольшой, поэтому фильтрация и сортировка выполняются в памяти
+	catalog := []entity.Merch{}
+	for _, item := range merch {
+		if item.Price < filter.MinPrice || (filter.MaxPrice > 0 && item.Price > filter.MaxPrice) {
+			continue
+		}
+		if filter.Category != "" && item.Category != filter.Category {
+			continue
+		}
+		catalog = append(catalog, item)
+	}
+	if sortable {
+		sort.SliceStable(catalog, func(i, j int) bool { return less(catalog[i], catalog[j]) })
+	}
+	return catalog, nil
+}
+
+func (u *merchUsecase) ListMerch(ctx context.Context, includeDeleted bool) ([]entity.Merch, error) {
+	u.logger.Info("ListMerch called", zap.Bool("includeDeleted", includeDeleted))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	return u.merchRepo.ListMerch(ctx, includeDeleted)
+}
+
+func (u *merchUsecase) GetMerch(ctx context.Context, merchID int) (entity.Merch, error) {
+	u.logger.Info("GetMerch called", zap.Int("merchID", merchID))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	merch, err := u.merchRepo.GetMerchByID(ctx, merchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Merch{}, ErrItemNotFound
+	}
+	if err != nil {
+		u.logger.Error("Error getting merch", zap.Error(err))
+		return entity.Merch{}, err
+	}
+	return merch, nil
+}
+
+func (u *merchUsecase) CreateMerch(ctx context.Context, merch entity.Merch) (entity.Merch, error) {
+	u.logger.Info("CreateMerch called", zap.String("name", merch.Name), zap.Int("price", merch.Price))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if !merchNamePattern.MatchString(merch.Name) {
+		return entity.Merch{}, ErrInvalidMerchName
+	}
+	if err := validateMerchDetails(merch); err != nil {
+		return entity.Merch{}, err
+	}
+
+	merchID, err := u.merchRepo.CreateMerch(ctx, merch)
+	if errors.Is(err, repository.ErrMerchExists) {
+		return entity.Merch{}, ErrMerchExists
+	}
+	if err != nil {
+		return entity.Merch{}, err
+	}
+
+	u.logger.Info("Successfully created merch", zap.String("name", merch.Name), zap.Int("merchID", merchID))
+	merch.ID = merchID
+	return merch, nil
+}
+
+// UpdateMerch меняет цену, описание и категорию товара. Название не меняется:
+// по нему строки inventory ссылаются на товар
+func (u *merchUsecase) UpdateMerch(ctx context.Context, merch entity.Merch) (entity.Merch, error) {
+	u.logger.Info("UpdateMerch called", zap.Int("merchID", merch.ID), zap.Int("price", merch.Price))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if err := validateMerchDetails(merch); err != nil {
+		return entity.Merch{}, err
+	}
+
+	err := u.merchRepo.UpdateMerch(ctx, merch)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Merch{}, ErrItemNotFound
+	}
+	if err != nil {
+		return entity.Merch{}, err
+	}
+
+	u.logger.Info("Successfully updated merch", zap.Int("merchID", merch.ID))
+	return u.GetMerch(ctx, merch.ID)
+}
+
+func (u *merchUsecase) DeleteMerch(ctx context.Context, merchID int) error {
+	u.logger.Info("DeleteMerch called", zap.Int("merchID", merchID))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	err := u.merchRepo.DeleteMerch(ctx, merchID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrItemNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	u.logger.Info("Successfully deleted merch", zap.Int("merchID", merchID))
+	return nil
+}
+
+func (u *merchUsecase) RestockMerch(ctx context.Context, merchID, quantity, employeeID int, comment string) (entity.Merch, error) {
+	u.logger.Info("RestockMerch called", zap.Int("merchID", merchID), zap.Int("quantity", quantity), zap.Int("employeeID", employeeID))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	if quantity <= 0 || quantity > maxRestockQuantity || utf8.RuneCountInString(comment) > maxRestockCommentLength {
+		return entity.Merch{}, ErrInvalidRestock
+	}
+
+	err := u.merchRepo.RestockMerch(ctx, merchID, quantity, employeeID, comment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return entity.Merch{}, ErrItemNotFound
+	}
+	if err != nil {
+		return entity.Merch{}, err
+	}
+
+	u.logger.Info("Successfully restocked merch", zap.Int("merchID", merchID), zap.Int("quantity", quantity))
+	return u.GetMerch(ctx, merchID)
+}
+
+func (u *merchUsecase) GetStockReport(ctx context.Context, merchID int) (entity.StockReport, error) {
+	u.logger.Info("GetStockReport called", zap.Int("merchID", merchID))
+	ctx, cancel := u.withTimeout(ctx)
+	defer cancel()
+	merch, err := u.GetMerch(ctx, merchID)
+	if err != nil {
+		return entity.StockReport{}, err
+	}
+
+	movements, err := u.merchRepo.ListStockMovements(ctx, merchID)
+	if err != nil {
+		return entity.StockReport{}, err
+	}
+	sold, err := u.merchRepo.GetMerchSold(ctx, merchID)
+	if err != nil {
+		return entity.StockReport{}, err
+	}
+
+	report := entity.StockReport{MerchID: merchID, Stock: merch.Stock, Sold: sold, Movements: movements}
+	for _, movement := range movements {
+		report.Restocked += movement.Quantity
+	}
+	if report.Restocked != report.Stock+report.Sold {
+		u.logger.Warn("Stock does not reconcile", zap.Int("merchID", merchID), zap.Int("restocked", report.Restocked), zap.Int("stock", report.Stock), zap.Int("sold", report.Sold))
+	}
+	return report, nil
+}
+
+func validateMerchDetails(merch entity.Merch) error {
+	if merch.Price <= 0 {
+		return ErrInvalidPrice
+	}
+	if !merchCategoryPattern.MatchString(merch.Category) {
+		return ErrInvalidMerchCategory
+	}
+	if utf8.RuneCountInString(merch.Description) > maxMerchDescriptionLength {
+		return ErrInvalidMerchDescription
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v4"
+	"github.com/qosmioo/merch-store/internal/entity"
+	"github.com/qosmioo/merch-store/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestListCatalog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	merch := []entity.Merch{
+		{ID: 1, Name: "t-shirt", Price: 80, Category: "clothing"},
+		{ID: 2, Name: "cup", Price: 20, Category: "accessories"},
+		{ID: 3, Name: "socks", Price: 10, Category: "clothing"},
+		{ID: 4, Name: "hoody", Price: 300, Category: "clothing"},
+	}
+	names := func(items []entity.Merch) []string {
+		result := []string{}
+		for _, item := range items {
+			result = append(result, item.Name)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		filter entity.MerchFilter
+		want   []string
+	}{
+		{"no filter", entity.MerchFilter{}, []string{"t-shirt", "cup", "socks", "hoody"}},
+		{"price range", entity.MerchFilter{MinPrice: 20, MaxPrice: 100}, []string{"t-shirt", "cup"}},
+		{"category sorted by price", entity.MerchFilter{Category: "clothing", Sort: "price"}, []string{"socks", "t-shirt", "hoody"}},
+		{"sorted by name desc", entity.MerchFilter{Sort: "-name"}, []string{"t-shirt", "socks", "hoody", "cup"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo.EXPECT().ListMerch(gomock.Any(), false).Return(append([]entity.Merch(nil), merch...), nil)
+			catalog, err := usecase.ListCatalog(context.Background(), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, names(catalog))
+		})
+	}
+}
+
+func TestListCatalog_InvalidFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	for _, filter := range []entity.MerchFilter{
+		{MinPrice: -1},
+		{MinPrice: 100, MaxPrice: 10},
+		{Sort: "stock"},
+	} {
+		_, err := usecase.ListCatalog(context.Background(), filter)
+		assert.ErrorIs(t, err, ErrInvalidMerchFilter)
+	}
+}
+
+func TestCreateMerch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	mockRepo.EXPECT().CreateMerch(gomock.Any(), entity.Merch{Name: "sticker", Price: 5}).Return(11, nil)
+
+	merch, err := usecase.CreateMerch(context.Background(), entity.Merch{Name: "sticker", Price: 5})
+	require.NoError(t, err)
+	assert.Equal(t, entity.Merch{ID: 11, Name: "sticker", Price: 5}, merch)
+}
+
+func TestCreateMerch_Validation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	tests := []struct {
+		name  string
+		merch entity.Merch
+		err   error
+	}{
+		{"zero price", entity.Merch{Name: "sticker", Price: 0}, ErrInvalidPrice},
+		{"negative price", entity.Merch{Name: "sticker", Price: -10}, ErrInvalidPrice},
+		{"empty name", entity.Merch{Name: "", Price: 10}, ErrInvalidMerchName},
+		{"name with slash", entity.Merch{Name: "a/b", Price: 10}, ErrInvalidMerchName},
+		{"category with spaces", entity.Merch{Name: "sticker", Price: 10, Category: "office supplies"}, ErrInvalidMerchCategory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usecase.CreateMerch(context.Background(), tt.merch)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestCreateMerch_Exists(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	mockRepo.EXPECT().CreateMerch(gomock.Any(), gomock.Any()).Return(0, repository.ErrMerchExists)
+
+	_, err := usecase.CreateMerch(context.Background(), entity.Merch{Name: "cup", Price: 20})
+	assert.ErrorIs(t, err, ErrMerchExists)
+}
+
+func TestUpdateMerch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	_, err := usecase.UpdateMerch(context.Background(), entity.Merch{ID: 2, Price: 0})
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	mockRepo.EXPECT().UpdateMerch(gomock.Any(), entity.Merch{ID: 2, Price: 25}).Return(nil)
+	mockRepo.EXPECT().GetMerchByID(gomock.Any(), 2).Return(entity.Merch{ID: 2, Name: "cup", Price: 25}, nil)
+	merch, err := usecase.UpdateMerch(context.Background(), entity.Merch{ID: 2, Price: 25})
+	require.NoError(t, err)
+	assert.Equal(t, 25, merch.Price)
+
+	mockRepo.EXPECT().UpdateMerch(gomock.Any(), entity.Merch{ID: 99, Price: 25}).Return(pgx.ErrNoRows)
+	_, err = usecase.UpdateMerch(context.Background(), entity.Merch{ID: 99, Price: 25})
+	assert.ErrorIs(t, err, ErrItemNotFound)
+}
+
+func TestDeleteMerch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	mockRepo.EXPECT().DeleteMerch(gomock.Any(), 2).Return(nil)
+	assert.NoError(t, usecase.DeleteMerch(context.Background(), 2))
+
+	mockRepo.EXPECT().DeleteMerch(gomock.Any(), 2).Return(pgx.ErrNoRows)
+	assert.ErrorIs(t, usecase.DeleteMerch(context.Background(), 2), ErrItemNotFound)
+}
+
+func TestRestockMerch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	_, err := usecase.RestockMerch(context.Background(), 2, 0, 1, "")
+	assert.ErrorIs(t, err, ErrInvalidRestock)
+
+	mockRepo.EXPECT().RestockMerch(gomock.Any(), 2, 50, 1, "поставка").Return(nil)
+	mockRepo.EXPECT().GetMerchByID(gomock.Any(), 2).Return(entity.Merch{ID: 2, Name: "cup", Stock: 50}, nil)
+	merch, err := usecase.RestockMerch(context.Background(), 2, 50, 1, "поставка")
+	require.NoError(t, err)
+	assert.Equal(t, 50, merch.Stock)
+
+	mockRepo.EXPECT().RestockMerch(gomock.Any(), 99, 5, 1, "").Return(pgx.ErrNoRows)
+	_, err = usecase.RestockMerch(context.Background(), 99, 5, 1, "")
+	assert.ErrorIs(t, err, ErrItemNotFound)
+}
+
+func TestGetStockReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := repository.NewMockMerchRepository(ctrl)
+	usecase := NewMerchUsecase(mockRepo, MerchConfig{}, zap.NewNop())
+
+	mockRepo.EXPECT().GetMerchByID(gomock.Any(), 2).Return(entity.Merch{ID: 2, Name: "cup", Stock: 70}, nil)
+	mockRepo.EXPECT().ListStockMovements(gomock.Any(), 2).Return([]entity.StockMovement{{Quantity: 100}, {Quantity: 20}}, nil)
+	mockRepo.EXPECT().GetMerchSold(gomock.Any(), 2).Return(50, nil)
+
+	report, err := usecase.GetStockReport(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, 120, report.Restocked)
+	assert.Equal(t, report.Restocked, report.Stock+report.Sold)
+}
@@ -0,0 +1,28 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"go.uber.org/zap"
+)
+
+// withTx выполняет fn в транзакции: откатывает её, если fn вернула ошибку, и
+// фиксирует в противном случае. Ошибка фиксации возвращается вызывающему,
+// иначе клиент получил бы успешный ответ на изменения, которые не сохранились
+func withTx(ctx context.Context, begin func(context.Context) (pgx.Tx, error), logger *zap.Logger, fn func(tx pgx.Tx) error) error {
+	tx, err := begin(ctx)
+	if err != nil {
+		logger.Error("Error starting transaction", zap.Error(err))
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		logger.Error("Error committing transaction", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -1 +1,2 @@
-DROP TABLE IF EXISTS employees; 
\ No newline at end of file
+-- Отменяет 0001. Выполняется после отмены всех миграций, которые ссылаются на employees
+DROP TABLE IF EXISTS employees;
@@ -1 +1,2 @@
-DROP TABLE IF EXISTS merch; 
\ No newline at end of file
+-- Вместе с таблицей удаляется начальный каталог, повторное применение 0002 создаёт его заново
+DROP TABLE IF EXISTS merch;
@@ -1,10 +1,11 @@
-CREATE TABLE merch (
+-- Создание таблицы merch, если она не существует
+CREATE TABLE IF NOT EXISTS merch (
     id SERIAL PRIMARY KEY,
     name VARCHAR(100) UNIQUE NOT NULL,
     price INT NOT NULL
 );
 
--- Вставка начальных данных
+-- Вставка начальных данных. Товары, которые уже есть в базе, не меняются
 INSERT INTO merch (name, price) VALUES
 ('t-shirt', 80),
 ('cup', 20),
@@ -15,4 +16,5 @@ INSERT INTO merch (name, price) VALUES
 ('umbrella', 200),
 ('socks', 10),
 ('wallet', 50),
-('pink-hoody', 500); 
\ No newline at end of file
+('pink-hoody', 500)
+ON CONFLICT (name) DO NOTHING; 
\ No newline at end of file
@@ -1 +1 @@
-DROP TABLE IF EXISTS inventory; 
\ No newline at end of file
+DROP TABLE IF EXISTS inventory;
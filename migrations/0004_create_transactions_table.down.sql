@@ -1 +1 @@
-DROP TABLE IF EXISTS transactions;
\ No newline at end of file
+DROP TABLE IF EXISTS transactions;
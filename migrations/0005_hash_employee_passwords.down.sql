@@ -0,0 +1,3 @@
+-- Хэши не превращаются обратно в пароли, а pgcrypto может использоваться
+-- другими схемами, поэтому отменяется только изменение типа колонки
+ALTER TABLE employees ALTER COLUMN password TYPE VARCHAR(100);
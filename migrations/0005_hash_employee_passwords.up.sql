@@ -0,0 +1,10 @@
+-- В колонке хранится хэш пароля вместе с параметрами алгоритма (bcrypt, argon2id),
+-- поэтому 100 символов может не хватить
+ALTER TABLE employees ALTER COLUMN password TYPE VARCHAR(255);
+
+-- Пароли, которые ещё хранятся открытым текстом, хэшируются bcrypt со стоимостью
+-- hasher.DefaultBcryptCost, открытых паролей в базе после миграции не остаётся
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+UPDATE employees SET password = crypt(password, gen_salt('bf', 10))
+WHERE password NOT LIKE '$2a$%' AND password NOT LIKE '$2b$%' AND password NOT LIKE '$2y$%'
+    AND password NOT LIKE '$argon2id$%';
@@ -0,0 +1 @@
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS employees_name_key; 
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Логин сотрудника должен быть уникальным. Если в таблице уже есть
+-- дубликаты, их нужно разрешить вручную до применения миграции
+ALTER TABLE employees ADD CONSTRAINT employees_name_key UNIQUE (name);
@@ -0,0 +1,2 @@
+ALTER TABLE employees DROP COLUMN IF EXISTS token_version;
+DROP TABLE IF EXISTS refresh_tokens; 
\ No newline at end of file
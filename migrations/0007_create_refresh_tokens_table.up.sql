@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    employee_id INT NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(id)
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_employee_id_idx ON refresh_tokens (employee_id);
+
+-- Версия токенов сотрудника: access-токены с другой версией считаются отозванными
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Роль определяет доступ к административным эндпоинтам; все существующие сотрудники получают роль employee
+ALTER TABLE employees ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'employee';
+
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS employees_role_check;
+ALTER TABLE employees ADD CONSTRAINT employees_role_check CHECK (role IN ('employee', 'admin', 'auditor'));
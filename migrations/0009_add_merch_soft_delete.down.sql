@@ -0,0 +1,2 @@
+ALTER TABLE merch DROP CONSTRAINT IF EXISTS merch_price_positive;
+ALTER TABLE merch DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,5 @@
+-- Снятые с продажи товары не удаляются: на их названия ссылаются строки inventory
+ALTER TABLE merch ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE merch DROP CONSTRAINT IF EXISTS merch_price_positive;
+ALTER TABLE merch ADD CONSTRAINT merch_price_positive CHECK (price > 0);
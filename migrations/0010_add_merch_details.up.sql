@@ -0,0 +1,14 @@
+ALTER TABLE merch ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE merch ADD COLUMN IF NOT EXISTS category VARCHAR(50) NOT NULL DEFAULT '';
+
+-- Категории и описания начального каталога
+UPDATE merch SET category = 'clothing', description = 'Футболка с логотипом' WHERE name = 't-shirt';
+UPDATE merch SET category = 'clothing', description = 'Худи с логотипом' WHERE name = 'hoody';
+UPDATE merch SET category = 'clothing', description = 'Розовое худи с логотипом' WHERE name = 'pink-hoody';
+UPDATE merch SET category = 'clothing', description = 'Носки с логотипом' WHERE name = 'socks';
+UPDATE merch SET category = 'accessories', description = 'Кружка с логотипом' WHERE name = 'cup';
+UPDATE merch SET category = 'accessories', description = 'Зонт с логотипом' WHERE name = 'umbrella';
+UPDATE merch SET category = 'accessories', description = 'Кошелёк с логотипом' WHERE name = 'wallet';
+UPDATE merch SET category = 'accessories', description = 'Внешний аккумулятор' WHERE name = 'powerbank';
+UPDATE merch SET category = 'stationery', description = 'Книга' WHERE name = 'book';
+UPDATE merch SET category = 'stationery', description = 'Ручка с логотипом' WHERE name = 'pen';
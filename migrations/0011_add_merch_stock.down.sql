@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stock_movements;
+ALTER TABLE merch DROP CONSTRAINT IF EXISTS merch_stock_non_negative;
+ALTER TABLE merch DROP COLUMN IF EXISTS stock;
@@ -0,0 +1,27 @@
+ALTER TABLE merch ADD COLUMN IF NOT EXISTS stock INT NOT NULL DEFAULT 0;
+
+ALTER TABLE merch DROP CONSTRAINT IF EXISTS merch_stock_non_negative;
+ALTER TABLE merch ADD CONSTRAINT merch_stock_non_negative CHECK (stock >= 0);
+
+-- Журнал пополнений склада. Для любого товара сумма quantity равна
+-- текущему остатку плюс количество этого товара в inventory
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id SERIAL PRIMARY KEY,
+    merch_id INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    employee_id INT,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (merch_id) REFERENCES merch(id),
+    FOREIGN KEY (employee_id) REFERENCES employees(id)
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_merch_id_idx ON stock_movements (merch_id);
+
+-- Начальный остаток: по 100 штук каждого товара сверх уже проданных
+UPDATE merch SET stock = 100 WHERE stock = 0;
+INSERT INTO stock_movements (merch_id, quantity, comment)
+SELECT m.id, m.stock + COALESCE(SUM(i.quantity), 0), 'initial stock'
+FROM merch m LEFT JOIN inventory i ON i.type = m.name
+WHERE NOT EXISTS (SELECT 1 FROM stock_movements sm WHERE sm.merch_id = m.id)
+GROUP BY m.id, m.stock;
@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_amount_positive;
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS employees_coins_non_negative;
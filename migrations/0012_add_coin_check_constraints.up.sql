@@ -0,0 +1,11 @@
+-- Вторая линия защиты после проверок в приложении. NOT VALID не проверяет
+-- строки, записанные до появления ограничений (в том числе переводы с
+-- отрицательной суммой), но действует для всех новых и изменённых строк.
+-- После исправления старых данных ограничения можно подтвердить:
+-- ALTER TABLE employees VALIDATE CONSTRAINT employees_coins_non_negative;
+-- ALTER TABLE transactions VALIDATE CONSTRAINT transactions_amount_positive;
+ALTER TABLE employees DROP CONSTRAINT IF EXISTS employees_coins_non_negative;
+ALTER TABLE employees ADD CONSTRAINT employees_coins_non_negative CHECK (coins >= 0) NOT VALID;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_amount_positive;
+ALTER TABLE transactions ADD CONSTRAINT transactions_amount_positive CHECK (amount > 0) NOT VALID;
@@ -0,0 +1,92 @@
+-- Двойная запись: каждая проводка (journal_entries) состоит из движений
+-- (ledger_postings) по счетам, сумма которых равна нулю. Положительная сумма
+-- увеличивает баланс счёта, отрицательная — уменьшает. Счёт эмиссии уходит в
+-- минус на количество выпущенных монет, поэтому сумма всех балансов всегда равна нулю
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('employee', 'store', 'issuance')),
+    employee_id INT UNIQUE,
+    FOREIGN KEY (employee_id) REFERENCES employees(id),
+    CHECK ((kind = 'employee') = (employee_id IS NOT NULL))
+);
+
+-- Счета магазина и эмиссии существуют в единственном экземпляре
+CREATE UNIQUE INDEX IF NOT EXISTS ledger_accounts_system_kind_idx ON ledger_accounts (kind) WHERE employee_id IS NULL;
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('opening', 'issuance', 'transfer', 'purchase')),
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ledger_postings (
+    id SERIAL PRIMARY KEY,
+    entry_id INT NOT NULL,
+    account_id INT NOT NULL,
+    amount INT NOT NULL CHECK (amount <> 0),
+    FOREIGN KEY (entry_id) REFERENCES journal_entries(id),
+    FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+);
+
+CREATE INDEX IF NOT EXISTS ledger_postings_entry_id_idx ON ledger_postings (entry_id);
+CREATE INDEX IF NOT EXISTS ledger_postings_account_id_idx ON ledger_postings (account_id);
+
+-- Журнал только дополняется: исправления оформляются новыми проводками
+CREATE OR REPLACE FUNCTION reject_ledger_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger is append-only: % on % is not allowed', TG_OP, TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS journal_entries_append_only ON journal_entries;
+CREATE TRIGGER journal_entries_append_only BEFORE UPDATE OR DELETE ON journal_entries
+    FOR EACH ROW EXECUTE FUNCTION reject_ledger_modification();
+
+DROP TRIGGER IF EXISTS ledger_postings_append_only ON ledger_postings;
+CREATE TRIGGER ledger_postings_append_only BEFORE UPDATE OR DELETE ON ledger_postings
+    FOR EACH ROW EXECUTE FUNCTION reject_ledger_modification();
+
+-- Баланс проводки проверяется при фиксации транзакции, когда записаны все её движения
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF (SELECT SUM(amount) FROM ledger_postings WHERE entry_id = NEW.entry_id) <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced', NEW.entry_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_postings_balanced ON ledger_postings;
+CREATE CONSTRAINT TRIGGER ledger_postings_balanced AFTER INSERT ON ledger_postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+INSERT INTO ledger_accounts (kind) VALUES ('store'), ('issuance') ON CONFLICT DO NOTHING;
+INSERT INTO ledger_accounts (kind, employee_id)
+SELECT 'employee', id FROM employees ON CONFLICT DO NOTHING;
+
+-- История покупок до появления журнала не восстанавливается: текущие балансы
+-- сотрудников переносятся одной вступительной проводкой со счёта эмиссии.
+-- Переносятся все ненулевые балансы, в том числе отрицательные из старых данных
+-- (ограничение 0012 их не проверяет), иначе журнал с ними не сойдётся.
+-- Если балансы в сумме равны нулю, движение по счёту эмиссии не нужно
+INSERT INTO journal_entries (kind, description)
+SELECT 'opening', 'opening balances'
+WHERE NOT EXISTS (SELECT 1 FROM journal_entries WHERE kind = 'opening')
+AND EXISTS (SELECT 1 FROM employees WHERE coins <> 0);
+
+WITH opening AS (
+    SELECT id FROM journal_entries WHERE kind = 'opening'
+    AND NOT EXISTS (SELECT 1 FROM ledger_postings p WHERE p.entry_id = journal_entries.id)
+)
+INSERT INTO ledger_postings (entry_id, account_id, amount)
+SELECT opening.id, a.id, e.coins
+FROM opening, employees e JOIN ledger_accounts a ON a.employee_id = e.id
+WHERE e.coins <> 0
+UNION ALL
+SELECT opening.id, a.id, -SUM(e.coins)
+FROM opening, employees e, ledger_accounts a
+WHERE a.kind = 'issuance'
+GROUP BY opening.id, a.id
+HAVING SUM(e.coins) <> 0;
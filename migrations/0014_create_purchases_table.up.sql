@@ -0,0 +1,12 @@
+-- История покупок: inventory хранит только количество, а здесь остаются
+-- цена на момент покупки и время. Покупки до появления таблицы не восстанавливаются
+CREATE TABLE IF NOT EXISTS purchases (
+    id SERIAL PRIMARY KEY,
+    employee_id INT NOT NULL,
+    item VARCHAR(100) NOT NULL,
+    price INT NOT NULL CHECK (price > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(id)
+);
+
+CREATE INDEX IF NOT EXISTS purchases_employee_id_idx ON purchases (employee_id);
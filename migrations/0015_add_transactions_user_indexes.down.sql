@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS transactions_to_user_id_idx;
+DROP INDEX IF EXISTS transactions_from_user_id_idx;
@@ -0,0 +1,4 @@
+-- История выбирается по отправителю или получателю и сортируется по времени,
+-- поэтому индексы включают created_at и id
+CREATE INDEX IF NOT EXISTS transactions_from_user_id_idx ON transactions (from_user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS transactions_to_user_id_idx ON transactions (to_user_id, created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS transactions_public_created_at_idx;
+ALTER TABLE transactions DROP COLUMN IF EXISTS public;
+ALTER TABLE transactions DROP COLUMN IF EXISTS message;
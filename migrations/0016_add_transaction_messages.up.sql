@@ -0,0 +1,6 @@
+-- Необязательное сообщение к переводу. public означает, что отправитель
+-- согласился показывать перевод на общей стене благодарностей
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS message VARCHAR(200) NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS public BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS transactions_public_created_at_idx ON transactions (created_at DESC, id DESC) WHERE public;
@@ -0,0 +1,16 @@
+-- Ответы на запросы с заголовком Idempotency-Key. Пока запрос выполняется,
+-- status равен NULL. fingerprint — sha256 метода, пути и тела запроса: повтор
+-- ключа с другим запросом отклоняется
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    employee_id INT NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status INT,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (employee_id, key),
+    FOREIGN KEY (employee_id) REFERENCES employees(id)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys (created_at);
@@ -0,0 +1,2 @@
+-- Покупка может включать несколько единиц товара, price остаётся ценой за единицу
+ALTER TABLE purchases ADD COLUMN IF NOT EXISTS quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0);
@@ -0,0 +1,10 @@
+-- Корзина сотрудника: товар и количество. Цена не хранится и берётся из
+-- каталога при оформлении заказа
+CREATE TABLE IF NOT EXISTS cart_items (
+    employee_id INT NOT NULL,
+    item VARCHAR(100) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (employee_id, item),
+    FOREIGN KEY (employee_id) REFERENCES employees(id)
+);
@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS содержит SQL-миграции, встроенные в бинарный файл. Их применяет
+// команда migrate
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultBcryptCost is the bcrypt work factor used for new password hashes
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+// Hasher hashes passwords and verifies them against stored hashes
+type Hasher interface {
+	// Hash returns an encoded hash of the password suitable for storage
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the stored hash
+	Verify(hash, password string) (bool, error)
+	// NeedsRehash reports whether the stored hash should be replaced by a fresh one
+	NeedsRehash(hash string) bool
+	// Recognizes reports whether the stored hash was produced by this hasher
+	Recognizes(hash string) bool
+}
+
+type chainHasher struct {
+	primary Hasher
+	legacy  []Hasher
+}
+
+// New returns a Hasher that produces hashes with primary and still accepts
+// hashes produced by any of the legacy hashers. Hashes that are not produced
+// by primary are reported by NeedsRehash so they can be upgraded on login
+func New(primary Hasher, legacy ...Hasher) Hasher {
+	return &chainHasher{primary: primary, legacy: legacy}
+}
+
+func (h *chainHasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+func (h *chainHasher) Verify(hash, password string) (bool, error) {
+	if h.primary.Recognizes(hash) {
+		return h.primary.Verify(hash, password)
+	}
+	for _, legacy := range h.legacy {
+		if legacy.Recognizes(hash) {
+			return legacy.Verify(hash, password)
+		}
+	}
+	return false, ErrUnsupportedHash
+}
+
+func (h *chainHasher) NeedsRehash(hash string) bool {
+	if !h.primary.Recognizes(hash) {
+		return true
+	}
+	return h.primary.NeedsRehash(hash)
+}
+
+func (h *chainHasher) Recognizes(hash string) bool {
+	if h.primary.Recognizes(hash) {
+		return true
+	}
+	for _, legacy := range h.legacy {
+		if legacy.Recognizes(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+// NewBcrypt returns a Hasher backed by bcrypt with the given cost
+func NewBcrypt(cost int) Hasher {
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *bcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+func (h *bcryptHasher) Recognizes(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
@@ -0,0 +1,110 @@
+package hasher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcrypt_HashAndVerify(t *testing.T) {
+	h := New(NewBcrypt(bcrypt.MinCost))
+
+	hash, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "password", hash)
+
+	ok, err := h.Verify(hash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = h.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, h.NeedsRehash(hash))
+}
+
+func TestBcrypt_NeedsRehashOnCostChange(t *testing.T) {
+	oldHash, err := NewBcrypt(bcrypt.MinCost).Hash("password")
+	assert.NoError(t, err)
+
+	h := New(NewBcrypt(bcrypt.MinCost + 1))
+	ok, err := h.Verify(oldHash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(oldHash))
+}
+
+func TestUnsupportedHashWithoutLegacy(t *testing.T) {
+	h := New(NewBcrypt(bcrypt.MinCost))
+
+	ok, err := h.Verify("password", "password")
+	assert.ErrorIs(t, err, ErrUnsupportedHash)
+	assert.False(t, ok)
+}
+
+// testArgon2Params keep the tests fast, production code uses DefaultArgon2Params
+var testArgon2Params = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2id_HashAndVerify(t *testing.T) {
+	h := New(NewArgon2id(testArgon2Params))
+
+	hash, err := h.Hash("password")
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+
+	ok, err := h.Verify(hash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = h.Verify(hash, "wrong")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.False(t, h.NeedsRehash(hash))
+}
+
+func TestArgon2id_NeedsRehashOnParamsChange(t *testing.T) {
+	oldHash, err := NewArgon2id(testArgon2Params).Hash("password")
+	assert.NoError(t, err)
+
+	params := testArgon2Params
+	params.Iterations++
+	h := New(NewArgon2id(params))
+	ok, err := h.Verify(oldHash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(oldHash))
+}
+
+func TestSwitchBetweenBcryptAndArgon2id(t *testing.T) {
+	bcryptHash, err := NewBcrypt(bcrypt.MinCost).Hash("password")
+	assert.NoError(t, err)
+	argon2Hash, err := NewArgon2id(testArgon2Params).Hash("password")
+	assert.NoError(t, err)
+
+	// Hashes of the previous algorithm still verify and are marked for an upgrade
+	h := New(NewArgon2id(testArgon2Params), NewBcrypt(bcrypt.MinCost))
+	ok, err := h.Verify(bcryptHash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(bcryptHash))
+	assert.False(t, h.NeedsRehash(argon2Hash))
+
+	h = New(NewBcrypt(bcrypt.MinCost), NewArgon2id(testArgon2Params))
+	ok, err = h.Verify(argon2Hash, "password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(argon2Hash))
+}
+
+func TestArgon2id_MalformedHash(t *testing.T) {
+	h := NewArgon2id(testArgon2Params)
+	for _, hash := range []string{"$argon2id$", "$argon2id$v=19$m=64,t=1,p=1$salt", "$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$a2V5"} {
+		ok, err := h.Verify(hash, "password")
+		assert.Error(t, err, hash)
+		assert.False(t, ok)
+		assert.True(t, h.NeedsRehash(hash))
+	}
+}
@@ -2,6 +2,9 @@ package jwt
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -9,30 +12,147 @@ import (
 	"github.com/dgrijalva/jwt-go"
 )
 
-var jwtKey = []byte("your_secret_key")
+var ErrInvalidToken = errors.New("invalid token")
+
+// Config — время жизни токенов, зарегистрированные claims и набор ключей.
+// Проверять токены можно любым ключом, новые подписываются ключом SigningKeyID
+type Config struct {
+	Issuer       string        `yaml:"issuer"`
+	Audience     string        `yaml:"audience"`
+	TTL          time.Duration `yaml:"ttl"`
+	SigningKeyID string        `yaml:"signing_key_id"`
+	Keys         []KeyConfig   `yaml:"keys"`
+}
 
 type Claims struct {
 	Username string `json:"username"`
+	Role     string `json:"role"`
+	// TokenVersion сверяется с версией сотрудника в базе, её увеличение отзывает все выданные токены
+	TokenVersion int `json:"ver"`
 	jwt.StandardClaims
 }
 
-func GenerateJWT(username string) (string, error) {
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &Claims{
-		Username: username,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+// Signer выдаёт подписанные access-токены
+type Signer interface {
+	Sign(claims *Claims) (string, error)
+}
+
+// Verifier проверяет access-токены и публикует открытую часть ключей. ctx —
+// контекст запроса, его учитывают реализации, которые обращаются к хранилищу.
+// Отклонённый токен возвращает ошибку с ErrInvalidToken, любая другая ошибка
+// означает, что токен проверить не удалось
+type Verifier interface {
+	Verify(ctx context.Context, tokenStr string) (*Claims, error)
+	JWKS() JWKS
+}
+
+// Manager подписывает и проверяет токены ключами из Config
+type Manager struct {
+	issuer     string
+	audience   string
+	ttl        time.Duration
+	signingKey *key
+	keys       map[string]*key
+	// keyOrder сохраняет порядок ключей из конфигурации для JWKS
+	keyOrder []*key
+}
+
+func NewManager(config Config) (*Manager, error) {
+	if config.TTL <= 0 {
+		return nil, errors.New("jwt: ttl must be positive")
+	}
+	if len(config.Keys) == 0 {
+		return nil, errors.New("jwt: at least one key is required")
+	}
+
+	m := &Manager{
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		ttl:      config.TTL,
+		keys:     make(map[string]*key, len(config.Keys)),
+	}
+	for _, keyConfig := range config.Keys {
+		k, err := loadKey(keyConfig)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := m.keys[k.id]; ok {
+			return nil, fmt.Errorf("jwt: duplicate key id %q", k.id)
+		}
+		m.keys[k.id] = k
+		m.keyOrder = append(m.keyOrder, k)
+	}
+
+	signingKey, ok := m.keys[config.SigningKeyID]
+	if !ok {
+		return nil, fmt.Errorf("jwt: signing key %q is not configured", config.SigningKeyID)
+	}
+	if signingKey.signKey == nil {
+		return nil, fmt.Errorf("jwt: signing key %q has no private key", config.SigningKeyID)
+	}
+	m.signingKey = signingKey
+
+	return m, nil
+}
+
+// Sign заполняет зарегистрированные claims и подписывает токен активным ключом
+func (m *Manager) Sign(claims *Claims) (string, error) {
+	now := time.Now()
+	claims.Issuer = m.issuer
+	claims.Audience = m.audience
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(m.ttl).Unix()
+
+	token := jwt.NewWithClaims(m.signingKey.method, claims)
+	token.Header["kid"] = m.signingKey.id
+	return token.SignedString(m.signingKey.signKey)
+}
+
+// Verify проверяет только подпись и стандартные claims, поэтому ctx не используется
+func (m *Manager) Verify(ctx context.Context, tokenStr string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, m.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	// exp, nbf и iat проверяются в StandardClaims.Valid, здесь остаются
+	// обязательность exp и совпадение iss/aud
+	if claims.ExpiresAt == 0 {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if m.issuer != "" && !claims.VerifyIssuer(m.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if m.audience != "" && !claims.VerifyAudience(m.audience, true) {
+		return nil, fmt.Errorf("%w: unexpected audience %q", ErrInvalidToken, claims.Audience)
+	}
+	return claims, nil
+}
+
+// keyFunc выбирает ключ проверки по kid из заголовка токена
+func (m *Manager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	k, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	// Алгоритм берётся из конфигурации ключа, а не из заголовка токена
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %q for key %q", token.Method.Alg(), kid)
+	}
+	return k.verifyKey, nil
 }
 
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func AuthMiddleware(verifier Verifier, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tokenStr := r.Header.Get("Authorization")
 		if tokenStr == "" {
-			http.Error(w, "Неавторизован", http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "Неавторизован")
 			return
 		}
 
@@ -41,13 +161,15 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			tokenStr = strings.TrimPrefix(tokenStr, bearerPrefix)
 		}
 
-		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			http.Error(w, "Неавторизован", http.StatusUnauthorized)
+		// 401 только для недействительного токена: при сбое хранилища клиент
+		// не должен выбрасывать действующую сессию
+		claims, err := verifier.Verify(r.Context(), tokenStr)
+		if errors.Is(err, ErrInvalidToken) {
+			writeError(w, http.StatusUnauthorized, "Неавторизован")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal server error")
 			return
 		}
 
@@ -55,3 +177,30 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
+
+// RequireRole пропускает запрос, только если роль из токена входит в roles.
+// Используется внутри AuthMiddleware, которая кладёт claims в контекст
+func RequireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("claims").(*Claims)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Неавторизован")
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		writeError(w, http.StatusForbidden, "Доступ запрещён")
+	}
+}
+
+// writeError отвечает в том же формате {"errors": "..."}, что и обработчики API
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"errors": message})
+}
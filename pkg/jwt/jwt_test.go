@@ -0,0 +1,270 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-secret-that-is-at-least-32-bytes"
+
+func hmacConfig(keys ...KeyConfig) Config {
+	return Config{
+		Issuer:       "merch-store",
+		Audience:     "merch-store",
+		TTL:          time.Hour,
+		SigningKeyID: keys[0].ID,
+		Keys:         keys,
+	}
+}
+
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func TestManager_SignAndVerifyHS256(t *testing.T) {
+	m, err := NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", Secret: testSecret}))
+	require.NoError(t, err)
+
+	token, err := m.Sign(&Claims{Username: "alice"})
+	require.NoError(t, err)
+
+	claims, err := m.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, "merch-store", claims.Issuer)
+	assert.Equal(t, "merch-store", claims.Audience)
+	assert.Empty(t, m.JWKS().Keys)
+}
+
+func TestManager_SecretFromEnv(t *testing.T) {
+	t.Setenv("TEST_JWT_SECRET", testSecret)
+	_, err := NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", SecretEnv: "TEST_JWT_SECRET"}))
+	assert.NoError(t, err)
+
+	t.Setenv("TEST_JWT_SECRET", "short")
+	_, err = NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", SecretEnv: "TEST_JWT_SECRET"}))
+	assert.Error(t, err)
+}
+
+func TestManager_KeyRotation(t *testing.T) {
+	oldKey := KeyConfig{ID: "old", Algorithm: "HS256", Secret: testSecret}
+	newKey := KeyConfig{ID: "new", Algorithm: "HS256", Secret: testSecret + "-rotated"}
+
+	before, err := NewManager(hmacConfig(oldKey))
+	require.NoError(t, err)
+	oldToken, err := before.Sign(&Claims{Username: "alice"})
+	require.NoError(t, err)
+
+	// Новый ключ подписывает, старый остаётся для проверки выданных токенов
+	during, err := NewManager(hmacConfig(newKey, oldKey))
+	require.NoError(t, err)
+	newToken, err := during.Sign(&Claims{Username: "bob"})
+	require.NoError(t, err)
+
+	_, err = during.Verify(context.Background(), oldToken)
+	assert.NoError(t, err)
+	_, err = during.Verify(context.Background(), newToken)
+	assert.NoError(t, err)
+
+	after, err := NewManager(hmacConfig(newKey))
+	require.NoError(t, err)
+	_, err = after.Verify(context.Background(), oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestManager_RS256AndJWKS(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	path := writePEM(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(privateKey))
+
+	m, err := NewManager(hmacConfig(KeyConfig{ID: "rsa", Algorithm: "RS256", PrivateKeyFile: path}))
+	require.NoError(t, err)
+
+	token, err := m.Sign(&Claims{Username: "alice"})
+	require.NoError(t, err)
+	_, err = m.Verify(context.Background(), token)
+	assert.NoError(t, err)
+
+	jwks := m.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "rsa", jwks.Keys[0].Kid)
+	assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+}
+
+func TestManager_EdDSAVerifyOnlyKey(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	issuer, err := NewManager(hmacConfig(KeyConfig{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: writePEM(t, "PRIVATE KEY", privateDER)}))
+	require.NoError(t, err)
+	token, err := issuer.Sign(&Claims{Username: "alice"})
+	require.NoError(t, err)
+
+	verifier, err := NewManager(Config{
+		Issuer:       "merch-store",
+		Audience:     "merch-store",
+		TTL:          time.Hour,
+		SigningKeyID: "hs",
+		Keys: []KeyConfig{
+			{ID: "hs", Algorithm: "HS256", Secret: testSecret},
+			{ID: "ed", Algorithm: "EdDSA", PublicKeyFile: writePEM(t, "PUBLIC KEY", publicDER)},
+		},
+	})
+	require.NoError(t, err)
+
+	claims, err := verifier.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", claims.Username)
+
+	jwks := verifier.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+}
+
+func TestManager_SigningKeyWithoutPrivateKey(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	_, err = NewManager(hmacConfig(KeyConfig{ID: "ed", Algorithm: "EdDSA", PublicKeyFile: writePEM(t, "PUBLIC KEY", publicDER)}))
+	assert.Error(t, err)
+}
+
+func TestManager_RejectsInvalidClaims(t *testing.T) {
+	m, err := NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", Secret: testSecret}))
+	require.NoError(t, err)
+
+	sign := func(claims jwt.StandardClaims, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{Username: "alice", StandardClaims: claims})
+		token.Header["kid"] = kid
+		signed, err := token.SignedString([]byte(testSecret))
+		require.NoError(t, err)
+		return signed
+	}
+	now := time.Now()
+	valid := jwt.StandardClaims{Issuer: "merch-store", Audience: "merch-store", ExpiresAt: now.Add(time.Hour).Unix()}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"wrong issuer", sign(jwt.StandardClaims{Issuer: "other", Audience: "merch-store", ExpiresAt: valid.ExpiresAt}, "k1")},
+		{"wrong audience", sign(jwt.StandardClaims{Issuer: "merch-store", Audience: "other", ExpiresAt: valid.ExpiresAt}, "k1")},
+		{"not yet valid", sign(jwt.StandardClaims{Issuer: "merch-store", Audience: "merch-store", ExpiresAt: valid.ExpiresAt, NotBefore: now.Add(time.Minute).Unix()}, "k1")},
+		{"expired", sign(jwt.StandardClaims{Issuer: "merch-store", Audience: "merch-store", ExpiresAt: now.Add(-time.Minute).Unix()}, "k1")},
+		{"missing exp", sign(jwt.StandardClaims{Issuer: "merch-store", Audience: "merch-store"}, "k1")},
+		{"unknown kid", sign(valid, "k2")},
+		{"missing kid", sign(valid, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.Verify(context.Background(), tt.token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	_, err = m.Verify(context.Background(), sign(valid, "k1"))
+	assert.NoError(t, err)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	m, err := NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", Secret: testSecret}))
+	require.NoError(t, err)
+	token, err := m.Sign(&Claims{Username: "alice"})
+	require.NoError(t, err)
+
+	handler := AuthMiddleware(m, func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value("claims").(*Claims)
+		require.True(t, ok)
+		assert.Equal(t, "alice", claims.Username)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer garbage")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// failingVerifier не может проверить токен, например из-за недоступной базы
+type failingVerifier struct {
+	*Manager
+}
+
+func (v failingVerifier) Verify(ctx context.Context, tokenStr string) (*Claims, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestAuthMiddleware_VerifierFailure(t *testing.T) {
+	m, err := NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", Secret: testSecret}))
+	require.NoError(t, err)
+	token, err := m.Sign(&Claims{Username: "alice"})
+	require.NoError(t, err)
+
+	handler := AuthMiddleware(failingVerifier{m}, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler must not be called")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestRequireRole(t *testing.T) {
+	m, err := NewManager(hmacConfig(KeyConfig{ID: "k1", Algorithm: "HS256", Secret: testSecret}))
+	require.NoError(t, err)
+
+	handler := AuthMiddleware(m, RequireRole(func(w http.ResponseWriter, r *http.Request) {}, "admin", "auditor"))
+
+	tests := []struct {
+		role string
+		code int
+	}{
+		{"admin", http.StatusOK},
+		{"auditor", http.StatusOK},
+		{"employee", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		token, err := m.Sign(&Claims{Username: "alice", Role: tt.role})
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		assert.Equal(t, tt.code, rec.Code, "role %q", tt.role)
+	}
+}
@@ -0,0 +1,145 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// minSecretLength — минимальная длина HMAC-секрета (RFC 7518, раздел 3.2)
+const minSecretLength = 32
+
+// KeyConfig описывает один ключ. HMAC-секрет задаётся в Secret или в
+// переменной окружения SecretEnv, асимметричные ключи читаются из PEM-файлов.
+// Ключ только с PublicKeyFile проверяет, но не подписывает токены: так при
+// ротации сохраняется выведенный из работы ключ
+type KeyConfig struct {
+	ID             string `yaml:"id"`
+	Algorithm      string `yaml:"algorithm"`
+	Secret         string `yaml:"secret"`
+	SecretEnv      string `yaml:"secret_env"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+}
+
+type key struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+func loadKey(config KeyConfig) (*key, error) {
+	if config.ID == "" {
+		return nil, errors.New("jwt: key id is required")
+	}
+
+	method := jwt.GetSigningMethod(config.Algorithm)
+	switch method.(type) {
+	case *jwt.SigningMethodHMAC:
+		return loadHMACKey(config, method)
+	case *jwt.SigningMethodRSA, *signingMethodEdDSA:
+		return loadAsymmetricKey(config, method)
+	default:
+		return nil, fmt.Errorf("jwt: key %q: unsupported algorithm %q", config.ID, config.Algorithm)
+	}
+}
+
+func loadHMACKey(config KeyConfig, method jwt.SigningMethod) (*key, error) {
+	secret := config.Secret
+	if config.SecretEnv != "" {
+		secret = os.Getenv(config.SecretEnv)
+	}
+	if len(secret) < minSecretLength {
+		return nil, fmt.Errorf("jwt: key %q: secret must be at least %d bytes", config.ID, minSecretLength)
+	}
+	return &key{id: config.ID, method: method, signKey: []byte(secret), verifyKey: []byte(secret)}, nil
+}
+
+func loadAsymmetricKey(config KeyConfig, method jwt.SigningMethod) (*key, error) {
+	k := &key{id: config.ID, method: method}
+
+	if config.PrivateKeyFile != "" {
+		privateKey, err := readPrivateKey(config.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", config.ID, err)
+		}
+		k.signKey = privateKey
+		k.verifyKey = privateKey.Public()
+	}
+	if config.PublicKeyFile != "" {
+		publicKey, err := readPublicKey(config.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", config.ID, err)
+		}
+		k.verifyKey = publicKey
+	}
+	if k.verifyKey == nil {
+		return nil, fmt.Errorf("jwt: key %q: private_key_file or public_key_file is required", config.ID)
+	}
+
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := k.verifyKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt: key %q: %s requires an RSA key", config.ID, method.Alg())
+		}
+	case *signingMethodEdDSA:
+		if _, ok := k.verifyKey.(ed25519.PublicKey); !ok {
+			return nil, fmt.Errorf("jwt: key %q: %s requires an Ed25519 key", config.ID, method.Alg())
+		}
+	}
+	return k, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return rsaKey, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", path, err)
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("parse private key %s: unsupported key type %T", path, parsed)
+	}
+	return signer, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return rsaKey, nil
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key %s: %w", path, err)
+	}
+	return parsed, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
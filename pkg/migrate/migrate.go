@@ -0,0 +1,433 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	// lockKey — ключ pg_advisory_lock. Пока один процесс применяет миграции,
+	// остальные ждут, поэтому несколько экземпляров можно запускать одновременно
+	lockKey = 7265726368
+
+	queryCreateTable = "CREATE TABLE IF NOT EXISTS schema_migrations (" +
+		"version INT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	queryLock            = "SELECT pg_advisory_lock($1)"
+	queryUnlock          = "SELECT pg_advisory_unlock($1)"
+	queryAppliedVersions = "SELECT version, name, applied_at FROM schema_migrations"
+	queryInsertVersion   = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+	queryDeleteVersion   = "DELETE FROM schema_migrations WHERE version = $1"
+
+	// codeUndefinedTable — код ошибки PostgreSQL для несуществующей таблицы
+	codeUndefinedTable = "42P01"
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration — пара файлов NNNN_name.up.sql и NNNN_name.down.sql. Down
+// полностью отменяет изменения Up
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status — состояние миграции в базе. Миграции, которые применены, но
+// отсутствуют в бинарном файле, возвращаются с пустыми Up и Down
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load читает миграции из корня fsys и сортирует их по версии. У каждой
+// миграции должны быть непустые файлы up и down, версии не повторяются
+func Load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		match := fileNamePattern.FindStringSubmatch(file)
+		if match == nil {
+			return nil, fmt.Errorf("migrate: %s: file name must look like NNNN_name.up.sql or NNNN_name.down.sql", file)
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migrate: %s: version must be a positive number", file)
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			return nil, fmt.Errorf("migrate: %s is empty", file)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migrate: migration %04d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// New создаёт Migrator для миграций, отсортированных по версии, как их возвращает Load
+func New(db *pgxpool.Pool, migrations []Migration, logger *zap.Logger) *Migrator {
+	return &Migrator{db: db, migrations: migrations, logger: logger}
+}
+
+// Latest возвращает версию последней миграции или 0, если миграций нет
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up применяет все миграции, которые ещё не применены
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down отменяет последнюю применённую миграцию
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		last := 0
+		for version := range applied {
+			if version > last {
+				last = version
+			}
+		}
+		if last == 0 {
+			m.logger.Info("No migrations to roll back")
+			return nil
+		}
+		migration, ok := m.find(last)
+		if !ok {
+			return fmt.Errorf("migrate: migration %d is applied but not embedded in this binary, it cannot be rolled back", last)
+		}
+		return m.run(ctx, conn, []step{{migration: migration, up: false}})
+	})
+}
+
+// Goto применяет или отменяет миграции так, чтобы последней применённой
+// стала version. Goto(ctx, 0) отменяет все миграции
+func (m *Migrator) Goto(ctx context.Context, version int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		steps, err := plan(m.migrations, applied, version)
+		if err != nil {
+			return err
+		}
+		return m.run(ctx, conn, steps)
+	})
+}
+
+// Force отмечает миграции с версией не больше version применёнными, а более
+// новые — неприменёнными, не выполняя их SQL. Нужна, чтобы перевести под
+// управление Migrator базу, схема которой создана без него
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		mark, unmark, err := forced(m.migrations, applied, version)
+		if err != nil {
+			return err
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+		for _, migration := range mark {
+			if _, err := tx.Exec(ctx, queryInsertVersion, migration.Version, migration.Name); err != nil {
+				return err
+			}
+		}
+		for _, v := range unmark {
+			if _, err := tx.Exec(ctx, queryDeleteVersion, v); err != nil {
+				return err
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return err
+		}
+		m.logger.Info("Migration versions forced", zap.Int("version", version), zap.Int("marked", len(mark)), zap.Int("unmarked", len(unmark)))
+		return nil
+	})
+}
+
+// Status возвращает состояние всех миграций, не изменяя базу
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	return status(m.migrations, applied), nil
+}
+
+// Pending возвращает число встроенных миграций, которые ещё не применены
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// withLock выполняет fn на отдельном соединении, удерживая advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, queryLock, lockKey); err != nil {
+		return fmt.Errorf("migrate: acquire lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), queryUnlock, lockKey); err != nil {
+			m.logger.Error("Error releasing migration lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, queryCreateTable); err != nil {
+		return fmt.Errorf("migrate: create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) run(ctx context.Context, conn *pgxpool.Conn, steps []step) error {
+	if len(steps) == 0 {
+		m.logger.Info("Database schema is up to date")
+		return nil
+	}
+	for _, s := range steps {
+		started := time.Now()
+		if err := apply(ctx, conn, s); err != nil {
+			return fmt.Errorf("migrate: %s: %w", s, err)
+		}
+		m.logger.Info("Migration finished", zap.String("migration", s.String()), zap.Duration("duration", time.Since(started)))
+	}
+	return nil
+}
+
+// apply выполняет одну миграцию и обновляет schema_migrations в одной транзакции
+func apply(ctx context.Context, conn *pgxpool.Conn, s step) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Без аргументов pgx использует простой протокол, который допускает
+	// несколько команд в одном запросе
+	if s.up {
+		if _, err := tx.Exec(ctx, s.migration.Up); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, queryInsertVersion, s.migration.Version, s.migration.Name); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(ctx, s.migration.Down); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, queryDeleteVersion, s.migration.Version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+type appliedVersion struct {
+	name      string
+	appliedAt time.Time
+}
+
+// appliedVersions читает schema_migrations. Если таблицы ещё нет, ни одна
+// миграция не считается применённой
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int]appliedVersion, error) {
+	rows, err := conn.Query(ctx, queryAppliedVersions)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == codeUndefinedTable {
+			return map[int]appliedVersion{}, nil
+		}
+		return nil, fmt.Errorf("migrate: read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedVersion)
+	for rows.Next() {
+		var version int
+		var v appliedVersion
+		if err := rows.Scan(&version, &v.name, &v.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = v
+	}
+	return applied, rows.Err()
+}
+
+type step struct {
+	migration Migration
+	up        bool
+}
+
+func (s step) String() string {
+	direction := "down"
+	if s.up {
+		direction = "up"
+	}
+	return fmt.Sprintf("%04d_%s %s", s.migration.Version, s.migration.Name, direction)
+}
+
+// plan возвращает шаги, после которых применены ровно миграции с версией не
+// больше target: сначала отмена лишних от новых к старым, затем применение
+// недостающих от старых к новым
+func plan(migrations []Migration, applied map[int]appliedVersion, target int) ([]step, error) {
+	known := make(map[int]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+	}
+	if target != 0 && !known[target] {
+		return nil, fmt.Errorf("migrate: unknown version %d", target)
+	}
+	for version := range applied {
+		if version > target && !known[version] {
+			return nil, fmt.Errorf("migrate: migration %d is applied but not embedded in this binary, it cannot be rolled back", version)
+		}
+	}
+
+	var steps []step
+	for i := len(migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[migrations[i].Version]; ok && migrations[i].Version > target {
+			steps = append(steps, step{migration: migrations[i], up: false})
+		}
+	}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+			steps = append(steps, step{migration: migration, up: true})
+		}
+	}
+	return steps, nil
+}
+
+// forced возвращает миграции, которые нужно записать в schema_migrations, и
+// версии, которые нужно из неё удалить, чтобы применёнными числились ровно
+// миграции с версией не больше target
+func forced(migrations []Migration, applied map[int]appliedVersion, target int) ([]Migration, []int, error) {
+	known := false
+	for _, migration := range migrations {
+		if migration.Version == target {
+			known = true
+		}
+	}
+	if target != 0 && !known {
+		return nil, nil, fmt.Errorf("migrate: unknown version %d", target)
+	}
+
+	var mark []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; !ok && migration.Version <= target {
+			mark = append(mark, migration)
+		}
+	}
+	var unmark []int
+	for version := range applied {
+		if version > target {
+			unmark = append(unmark, version)
+		}
+	}
+	sort.Ints(unmark)
+	return mark, unmark, nil
+}
+
+func status(migrations []Migration, applied map[int]appliedVersion) []Status {
+	statuses := make([]Status, 0, len(migrations))
+	known := make(map[int]bool, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		v, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: v.appliedAt})
+	}
+	for version, v := range applied {
+		if !known[version] {
+			statuses = append(statuses, Status{Migration: Migration{Version: version, Name: v.name}, Applied: true, AppliedAt: v.appliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses
+}
@@ -0,0 +1,191 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "create_employees", Up: "CREATE TABLE employees ();", Down: "DROP TABLE employees;"},
+		{Version: 2, Name: "create_merch", Up: "CREATE TABLE merch ();", Down: "DROP TABLE merch;"},
+		{Version: 3, Name: "add_merch_stock", Up: "ALTER TABLE merch ADD stock INT;", Down: "ALTER TABLE merch DROP stock;"},
+	}
+}
+
+func applied(versions ...int) map[int]appliedVersion {
+	result := make(map[int]appliedVersion)
+	for _, version := range versions {
+		result[version] = appliedVersion{appliedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return result
+}
+
+func stepNames(steps []step) []string {
+	var names []string
+	for _, s := range steps {
+		names = append(names, s.String())
+	}
+	return names
+}
+
+func migrationVersions(migrations []Migration) []int {
+	var result []int
+	for _, migration := range migrations {
+		result = append(result, migration.Version)
+	}
+	return result
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0002_create_merch.up.sql":       file("CREATE TABLE merch ();"),
+		"0002_create_merch.down.sql":     file("DROP TABLE merch;"),
+		"0001_create_employees.up.sql":   file("CREATE TABLE employees ();"),
+		"0001_create_employees.down.sql": file("DROP TABLE employees;"),
+		"migrations.go":                  file("package migrations"),
+	})
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "create_employees", Up: "CREATE TABLE employees ();", Down: "DROP TABLE employees;"}, migrations[0])
+	assert.Equal(t, 2, migrations[1].Version)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		message string
+	}{
+		{
+			name:    "missing down",
+			fsys:    fstest.MapFS{"0001_create_employees.up.sql": file("CREATE TABLE employees ();")},
+			message: "migrate: migration 0001_create_employees must have both up and down files",
+		},
+		{
+			name: "empty down",
+			fsys: fstest.MapFS{
+				"0001_create_employees.up.sql":   file("CREATE TABLE employees ();"),
+				"0001_create_employees.down.sql": file("\n"),
+			},
+			message: "migrate: 0001_create_employees.down.sql is empty",
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_create_employees.up.sql": file("CREATE TABLE employees ();"),
+				"0001_create_merch.up.sql":     file("CREATE TABLE merch ();"),
+			},
+			message: "migrate: version 1 is used by both create_employees and create_merch",
+		},
+		{
+			name:    "bad file name",
+			fsys:    fstest.MapFS{"create_employees.sql": file("CREATE TABLE employees ();")},
+			message: "migrate: create_employees.sql: file name must look like NNNN_name.up.sql or NNNN_name.down.sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			assert.EqualError(t, err, tt.message)
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied map[int]appliedVersion
+		target  int
+		steps   []string
+	}{
+		{
+			name:    "up from empty database",
+			applied: applied(),
+			target:  3,
+			steps:   []string{"0001_create_employees up", "0002_create_merch up", "0003_add_merch_stock up"},
+		},
+		{
+			name:    "up to date",
+			applied: applied(1, 2, 3),
+			target:  3,
+			steps:   nil,
+		},
+		{
+			name:    "down to version in reverse order",
+			applied: applied(1, 2, 3),
+			target:  1,
+			steps:   []string{"0003_add_merch_stock down", "0002_create_merch down"},
+		},
+		{
+			name:    "down to zero",
+			applied: applied(1, 2),
+			target:  0,
+			steps:   []string{"0002_create_merch down", "0001_create_employees down"},
+		},
+		{
+			name:    "fills a gap",
+			applied: applied(1, 3),
+			target:  3,
+			steps:   []string{"0002_create_merch up"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := plan(testMigrations(), tt.applied, tt.target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.steps, stepNames(steps))
+		})
+	}
+}
+
+func TestPlan_UnknownVersions(t *testing.T) {
+	_, err := plan(testMigrations(), applied(), 7)
+	assert.EqualError(t, err, "migrate: unknown version 7")
+
+	// Миграцию, которой нет в бинарном файле, нельзя отменить
+	_, err = plan(testMigrations(), applied(1, 2, 3, 4), 3)
+	assert.EqualError(t, err, "migrate: migration 4 is applied but not embedded in this binary, it cannot be rolled back")
+
+	// Но она не мешает применять остальные миграции
+	withoutMerch := []Migration{testMigrations()[0], testMigrations()[2]}
+	steps, err := plan(withoutMerch, applied(1, 2), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0003_add_merch_stock up"}, stepNames(steps))
+}
+
+func TestForced(t *testing.T) {
+	mark, unmark, err := forced(testMigrations(), applied(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, migrationVersions(mark))
+	assert.Empty(t, unmark)
+
+	// Более новые версии, в том числе отсутствующие в бинарном файле, снимаются без выполнения down
+	mark, unmark, err = forced(testMigrations(), applied(1, 3, 4), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, migrationVersions(mark))
+	assert.Equal(t, []int{3, 4}, unmark)
+
+	_, _, err = forced(testMigrations(), applied(), 7)
+	assert.EqualError(t, err, "migrate: unknown version 7")
+}
+
+func TestStatus(t *testing.T) {
+	applied := applied(1, 4)
+	statuses := status(testMigrations(), applied)
+	require.Len(t, statuses, 4)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+	assert.False(t, statuses[2].Applied)
+	assert.Equal(t, 4, statuses[3].Version)
+	assert.True(t, statuses[3].Applied)
+	assert.Empty(t, statuses[3].Up)
+}